@@ -0,0 +1,226 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"toggl-test-wiliam/model"
+)
+
+type principalKey struct{}
+
+// Principal is an authenticated caller and the tenant it acts for
+type Principal struct {
+	Name   string
+	Tenant string
+}
+
+// jwtClaims are the claims checked on incoming tokens, "tenant" being ours
+type jwtClaims struct {
+	Subject   string `json:"sub"`
+	Tenant    string `json:"tenant,omitempty"`
+	ExpiresAt int64  `json:"exp"`
+	NotBefore int64  `json:"nbf"`
+}
+
+// ParseAPIKeys reads a comma separated list of "name:key" or
+// "tenant/name:key" pairs, as given in the API_KEYS environment variable,
+// into a map from key to its holder
+func ParseAPIKeys(spec string) (map[string]Principal, error) {
+	keys := map[string]Principal{}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		name, key, found := strings.Cut(pair, ":")
+		if !found || name == "" || key == "" {
+			return nil, fmt.Errorf("invalid api key %q, expected name:key", pair)
+		}
+
+		holder := Principal{Name: name}
+		if tenant, name, found := strings.Cut(name, "/"); found {
+			if tenant == "" || name == "" {
+				return nil, fmt.Errorf("invalid api key %q, expected tenant/name:key", pair)
+			}
+			holder = Principal{Name: name, Tenant: tenant}
+		}
+		keys[key] = holder
+	}
+	return keys, nil
+}
+
+// authEnabled reports whether API keys or a JWT secret are configured
+func (s *Server) authEnabled() bool {
+	return len(s.APIKeys) > 0 || len(s.JWTSecret) > 0
+}
+
+// authenticate rejects requests without a valid "X-API-Key" header or
+// "Authorization: Bearer" JWT once authentication is configured, and passes
+// the name of the authenticated caller on to the handlers. The card artwork
+// stays public, as image tags cannot send credentials
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.authEnabled() || strings.HasPrefix(r.URL.Path, "/static/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		caller, err := s.principalOf(r.Header.Get("X-API-Key"), r.Header.Get("Authorization"))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="deck"`)
+			http.Error(w, "Missing or invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, caller)))
+	})
+}
+
+// principalOf checks the credentials sent as an API key or an
+// "Authorization: Bearer" JWT, through headers or gRPC metadata alike
+func (s *Server) principalOf(key, authorization string) (Principal, error) {
+	if key != "" {
+		for known, holder := range s.APIKeys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(known)) == 1 {
+				return holder, nil
+			}
+		}
+		return Principal{}, errors.New("unknown api key")
+	}
+
+	token, found := strings.CutPrefix(authorization, "Bearer ")
+	if !found || len(s.JWTSecret) == 0 {
+		return Principal{}, errors.New("missing credentials")
+	}
+	return s.verifyJWT(token)
+}
+
+// verifyJWT checks an HS256 signed token against the JWT secret and its
+// exp and nbf claims against the clock, returning its subject and tenant
+func (s *Server) verifyJWT(token string) (Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return Principal{}, errors.New("unsupported token header")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, err
+	}
+	mac := hmac.New(sha256.New, s.JWTSecret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return Principal{}, errors.New("invalid signature")
+	}
+
+	claims := jwtClaims{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Principal{}, err
+	}
+	now := s.Now().Unix()
+	if claims.ExpiresAt != 0 && now >= claims.ExpiresAt {
+		return Principal{}, errors.New("token expired")
+	}
+	if claims.NotBefore != 0 && now < claims.NotBefore {
+		return Principal{}, errors.New("token not valid yet")
+	}
+	if claims.Subject == "" {
+		return Principal{}, errors.New("token has no subject")
+	}
+	return Principal{Name: claims.Subject, Tenant: claims.Tenant}, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// principalIn is the authenticated caller of a request context, empty when
+// authentication is off
+func principalIn(ctx context.Context) Principal {
+	caller, _ := ctx.Value(principalKey{}).(Principal)
+	return caller
+}
+
+// principal is the authenticated caller, empty when authentication is off
+func principal(r *http.Request) string {
+	return principalIn(r.Context()).Name
+}
+
+// tenantOf is the tenant of the authenticated caller; callers without one,
+// and every caller while authentication is off, share the default tenant ""
+func tenantOf(r *http.Request) string {
+	return principalIn(r.Context()).Tenant
+}
+
+// caller is who a deck operation acts for, whichever API it came through:
+// the authenticated principal and the deck token it holds
+type caller struct {
+	Principal
+	token string
+}
+
+// callerOf reads the caller of an HTTP request, with its "X-Deck-Token"
+// header or "token" query parameter
+func callerOf(r *http.Request) caller {
+	token := r.Header.Get("X-Deck-Token")
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	return caller{Principal: principalIn(r.Context()), token: token}
+}
+
+// id is the identity cards are shown to, empty while authentication is off:
+// a name the caller merely claims would let anyone read anyone's cards
+func (c caller) id() string {
+	return c.Name
+}
+
+// errUnauthenticatedOwner answers owner-only visibility asked for without
+// authentication, since nobody could ever prove to be the owner
+var errUnauthenticatedOwner = &deckError{status: http.StatusForbidden, message: "Owner visibility needs an authenticated caller"}
+
+// canOwn fails for owner-only visibility unless the caller is authenticated
+func (c caller) canOwn(visibility string) error {
+	if visibility == model.VisibilityOwner && c.Name == "" {
+		return errUnauthenticatedOwner
+	}
+	return nil
+}
+
+// stamp records the principal as the creator of the deck, in its tenant
+func (p Principal) stamp(deck *model.Deck) {
+	deck.CreatedBy = p.Name
+	deck.Tenant = p.Tenant
+}
+
+// SignJWT issues an HS256 token for the caller expiring at expiresAt; it is
+// meant for tooling and tests, the service itself only verifies tokens
+func SignJWT(secret []byte, caller Principal, expiresAt time.Time) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	claims, _ := json.Marshal(jwtClaims{Subject: caller.Name, Tenant: caller.Tenant, ExpiresAt: expiresAt.Unix()})
+	payload := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,129 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+	api "toggl-test-wiliam/api"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func authRequest(t *testing.T, method, url, header, value string) *http.Response {
+	req, err := http.NewRequest(method, url, nil)
+	assert.NoError(t, err)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	return resp
+}
+
+// playerKeys authenticates each player with the API key "<name>-key"
+var playerKeys = map[string]api.Principal{
+	"alice-key": {Name: "alice"},
+	"bob-key":   {Name: "bob"},
+	"carol-key": {Name: "carol"},
+}
+
+// asPlayer sends a request authenticated through playerKeys
+func asPlayer(t *testing.T, method, url, player string) *http.Response {
+	return authRequest(t, method, url, "X-API-Key", player+"-key")
+}
+
+func TestAuth_APIKey(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+	testSuite.server.APIKeys = map[string]api.Principal{"secret-key": {Name: "alice"}}
+
+	resp := authRequest(t, "POST", testSuite.ts.URL+"/deck", "", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("WWW-Authenticate"))
+
+	resp = authRequest(t, "POST", testSuite.ts.URL+"/deck", "X-API-Key", "wrong-key")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = authRequest(t, "POST", testSuite.ts.URL+"/deck?owner=bob", "X-API-Key", "secret-key")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	resp = authRequest(t, "GET", testSuite.ts.URL+"/deck/"+deck.ID, "X-API-Key", "secret-key")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	opened := api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&opened))
+	assert.Equal(t, "alice", opened.CreatedBy)
+
+	// The card artwork stays reachable from image tags
+	resp = authRequest(t, "GET", testSuite.ts.URL+"/static/cards/AS.svg", "", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
+
+func TestAuth_JWT(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+	secret := []byte("jwt-secret")
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	testSuite.server.JWTSecret = secret
+	testSuite.server.Now = func() time.Time { return now }
+
+	token := api.SignJWT(secret, api.Principal{Name: "carol"}, now.Add(time.Hour))
+	resp := authRequest(t, "POST", testSuite.ts.URL+"/deck", "Authorization", "Bearer "+token)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	resp = authRequest(t, "GET", testSuite.ts.URL+"/deck", "Authorization", "Bearer "+token)
+	list := api.ListDecksSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	assert.Len(t, list.Decks, 1)
+	assert.Equal(t, "carol", list.Decks[0].CreatedBy)
+
+	expired := api.SignJWT(secret, api.Principal{Name: "carol"}, now.Add(-time.Minute))
+	resp = authRequest(t, "GET", testSuite.ts.URL+"/deck/"+deck.ID, "Authorization", "Bearer "+expired)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	forged := api.SignJWT([]byte("other-secret"), api.Principal{Name: "carol"}, now.Add(time.Hour))
+	resp = authRequest(t, "GET", testSuite.ts.URL+"/deck/"+deck.ID, "Authorization", "Bearer "+forged)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
+
+func TestAuth_PlayerComesFromCredentials(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+	testSuite.server.APIKeys = map[string]api.Principal{"alice-key": {Name: "alice"}, "bob-key": {Name: "bob"}}
+
+	resp := authRequest(t, "POST", testSuite.ts.URL+"/deck?cards=AS,KD&visibility=owner", "X-API-Key", "alice-key")
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	// Created decks belong to their creator unless told otherwise
+	resp = authRequest(t, "GET", testSuite.ts.URL+"/deck/"+deck.ID+"/export", "X-API-Key", "alice-key")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Claiming another player in the query does not fool the visibility checks
+	resp = authRequest(t, "GET", testSuite.ts.URL+"/deck/"+deck.ID+"/export?player=alice", "X-API-Key", "bob-key")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
+
+func TestParseAPIKeys(t *testing.T) {
+	keys, err := api.ParseAPIKeys("alice:k1, studio/bob:k2,")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]api.Principal{
+		"k1": {Name: "alice"},
+		"k2": {Name: "bob", Tenant: "studio"},
+	}, keys)
+
+	_, err = api.ParseAPIKeys("alice")
+	assert.Error(t, err)
+	_, err = api.ParseAPIKeys("/alice:k1")
+	assert.Error(t, err)
+}
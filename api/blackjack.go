@@ -0,0 +1,342 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"toggl-test-wiliam/game/blackjack"
+	"toggl-test-wiliam/model"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type BlackjackHandSerializer struct {
+	Cards   []string `json:"cards"`
+	Total   int      `json:"total"`
+	Soft    bool     `json:"soft,omitempty"`
+	Bet     int      `json:"bet,omitempty"`
+	Doubled bool     `json:"doubled,omitempty"`
+	Split   bool     `json:"split,omitempty"`
+	Done    bool     `json:"done,omitempty"`
+	Result  string   `json:"result,omitempty"`
+	Payout  int      `json:"payout,omitempty"`
+}
+
+type BlackjackSerializer struct {
+	ID               string                    `json:"game_id"`
+	Round            int                       `json:"round"`
+	Status           string                    `json:"status"`
+	ActiveHand       int                       `json:"active_hand"`
+	DealerHitsSoft17 bool                      `json:"dealer_hits_soft_17"`
+	ShoeRemaining    int                       `json:"shoe_remaining"`
+	Hands            []BlackjackHandSerializer `json:"hands"`
+	Dealer           BlackjackHandSerializer   `json:"dealer"`
+}
+
+// CreateBlackjackGame builds a shuffled shoe of "decks" French decks, opens a
+// table on it and deals the first round for "bet"
+func (s *Server) CreateBlackjackGame(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	decks := 6
+	if decksParam := query.Get("decks"); decksParam != "" {
+		var err error
+		if decks, err = strconv.Atoi(decksParam); err != nil {
+			http.Error(w, "invalid decks: "+decksParam, http.StatusBadRequest)
+			return
+		}
+	}
+
+	hitsSoft17 := false
+	if h17Param := query.Get("h17"); h17Param != "" {
+		var err error
+		if hitsSoft17, err = strconv.ParseBool(h17Param); err != nil {
+			http.Error(w, "invalid h17: "+h17Param, http.StatusBadRequest)
+			return
+		}
+	}
+
+	bet, err := strconv.Atoi(query.Get("bet"))
+	if err != nil {
+		http.Error(w, blackjack.ErrInvalidBet.Error(), http.StatusBadRequest)
+		return
+	}
+
+	codes := s.frenchCodes()
+	shoe, err := blackjack.NewShoe(codes, decks)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if shoe.ExpiresAt, err = s.expiresAt(query.Get("ttl")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	stampCreator(r, &shoe)
+
+	game := blackjack.NewGame(shoe.ID, hitsSoft17)
+	game.Tenant = shoe.Tenant
+	game.CreatedBy = shoe.CreatedBy
+	game.Decks = decks
+	dealer := &shoeDealer{s: s, game: game, codes: codes}
+	dealer.switchTo(shoe)
+	if err := game.Deal(bet, dealer.draw); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.transaction(s.DB, func(tx *gorm.DB) error {
+		if err := dealer.save(tx); err != nil {
+			return err
+		}
+		return tx.Create(game).Error
+	})
+	if err != nil {
+		s.Logger.Printf("create blackjack game: %v", err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(blackjackResponse(game, dealer.shoe()))
+}
+
+// OpenBlackjackGame shows the table, keeping the dealer's hole card hidden until the player is done
+func (s *Server) OpenBlackjackGame(w http.ResponseWriter, r *http.Request) {
+	game, shoe, ok := s.findBlackjackGame(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(blackjackResponse(game, shoe))
+}
+
+// blackjackMove is a player decision taken on a game
+type blackjackMove func(r *http.Request, game *blackjack.Game, dealer *shoeDealer) error
+
+// blackjackAction applies a player decision, dealing from the game's shoe as needed
+func (s *Server) blackjackAction(move blackjackMove) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		game, shoe, ok := s.findBlackjackGame(w, r)
+		if !ok {
+			return
+		}
+
+		dealer := &shoeDealer{s: s, game: game, codes: s.frenchCodes(), shoes: []*dealtShoe{{deck: shoe}}}
+		if err := move(r, game, dealer); err != nil {
+			status := http.StatusConflict
+			if errors.Is(err, blackjack.ErrInvalidBet) {
+				status = http.StatusBadRequest
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		err := s.transaction(s.DB, func(tx *gorm.DB) error {
+			if err := dealer.save(tx); err != nil {
+				return err
+			}
+			return tx.Save(game).Error
+		})
+		if err != nil {
+			s.Logger.Printf("save blackjack game %s: %v", game.ID, err)
+			http.Error(w, "database error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(blackjackResponse(game, dealer.shoe()))
+	}
+}
+
+// dealBlackjackRound starts the next round for the "bet" query parameter,
+// on a fresh shoe once the cut card came out
+func dealBlackjackRound(r *http.Request, game *blackjack.Game, dealer *shoeDealer) error {
+	bet, err := strconv.Atoi(r.URL.Query().Get("bet"))
+	if err != nil {
+		return blackjack.ErrInvalidBet
+	}
+	if game.Status != blackjack.StatusPlayerTurn && blackjack.PastCutCard(dealer.shoe().Remaining, game.Decks*len(dealer.codes)) {
+		if err := dealer.reshuffle(nil); err != nil {
+			return err
+		}
+	}
+	return game.Deal(bet, dealer.draw)
+}
+
+func hitBlackjack(r *http.Request, game *blackjack.Game, dealer *shoeDealer) error {
+	return game.Hit(dealer.draw)
+}
+
+func standBlackjack(r *http.Request, game *blackjack.Game, dealer *shoeDealer) error {
+	return game.Stand(dealer.draw)
+}
+
+func doubleBlackjack(r *http.Request, game *blackjack.Game, dealer *shoeDealer) error {
+	return game.Double(dealer.draw)
+}
+
+func splitBlackjack(r *http.Request, game *blackjack.Game, dealer *shoeDealer) error {
+	return game.Split(dealer.draw)
+}
+
+// shoeDealer deals a blackjack game from its shoe, moving on to a freshly
+// shuffled one when asked to between rounds or when the shoe runs out in the
+// middle of one. Every shoe is a face down deck of the game, and the shoes it
+// moved on from are deleted so a game only ever holds one live deck
+type shoeDealer struct {
+	s     *Server
+	game  *blackjack.Game
+	codes []string
+	shoes []*dealtShoe
+}
+
+// dealtShoe is a shoe with the events to record against it, created by
+// the dealer unless it was loaded
+type dealtShoe struct {
+	deck    model.Deck
+	events  []model.DeckEvent
+	created bool
+}
+
+func (d *shoeDealer) shoe() model.Deck {
+	return d.shoes[len(d.shoes)-1].deck
+}
+
+func (d *shoeDealer) draw() (model.Card, error) {
+	current := d.shoes[len(d.shoes)-1]
+	if current.deck.Remaining == 0 {
+		// The discards are shuffled back, the cards on the table stay there
+		if err := d.reshuffle(d.game.InPlay()); err != nil {
+			return model.Card{}, err
+		}
+		current = d.shoes[len(d.shoes)-1]
+		if current.deck.Remaining == 0 {
+			return model.Card{}, blackjack.ErrShoeExhausted
+		}
+	}
+
+	cards, err := current.deck.Draw(1)
+	if err != nil {
+		return model.Card{}, err
+	}
+	current.events = append(current.events, model.DeckEvent{Type: model.EventDrawn, Count: 1, Cards: model.CardCodes(cards)})
+	return cards[0], nil
+}
+
+// reshuffle moves the game on to a fresh shoe without the cards in play,
+// living as long as the one it replaces
+func (d *shoeDealer) reshuffle(inPlay []string) error {
+	previous := d.shoe()
+	shoe, err := blackjack.RefillShoe(d.codes, d.game.Decks, inPlay)
+	if err != nil {
+		return err
+	}
+	shoe.Tenant = previous.Tenant
+	shoe.CreatedBy = previous.CreatedBy
+	shoe.ExpiresAt = previous.ExpiresAt
+	d.switchTo(shoe)
+	return nil
+}
+
+// switchTo shuffles a new shoe and deals from it from now on
+func (d *shoeDealer) switchTo(shoe model.Deck) {
+	shoe.GameID = d.game.ID
+	shoe.Visibility = model.VisibilityFaceDown
+	events := []model.DeckEvent{{Type: model.EventCreated, Cards: model.CardCodes(shoe.Cards)}}
+	seed := d.s.shuffleSeed()
+	shoe.ShuffleSeed(seed)
+	events = append(events, model.DeckEvent{Type: model.EventShuffled, Seed: seed})
+
+	d.game.DeckID = shoe.ID
+	d.shoes = append(d.shoes, &dealtShoe{deck: shoe, events: events, created: true})
+}
+
+// save stores every shoe dealt from with its events, inside the caller's
+// transaction, soft deleting all but the one the game deals from now
+func (d *shoeDealer) save(tx *gorm.DB) error {
+	now := d.s.Now().UTC()
+	for i, shoe := range d.shoes {
+		if i < len(d.shoes)-1 {
+			shoe.deck.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+		}
+		save := tx.Save
+		if shoe.created {
+			save = tx.Create
+		}
+		if err := save(&shoe.deck).Error; err != nil {
+			return err
+		}
+		if err := d.s.recordEvents(tx, shoe.deck.ID, shoe.events...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// frenchCodes lists the codes of a French deck, the shared card type games deal
+func (s *Server) frenchCodes() []string {
+	var codes []string
+	s.DB.Model(&model.Card{}).Where("card_type = ?", "FRENCH").Pluck("code", &codes)
+	return codes
+}
+
+// findBlackjackGame loads the game and its shoe for the player who opened it
+func (s *Server) findBlackjackGame(w http.ResponseWriter, r *http.Request) (*blackjack.Game, model.Deck, bool) {
+	game := &blackjack.Game{}
+	if err := inTenant(s.DB, tenantOf(r)).First(game, "id = ?", mux.Vars(r)["game_id"]).Error; err != nil {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return nil, model.Deck{}, false
+	}
+	if game.CreatedBy != "" && game.CreatedBy != principal(r) {
+		http.Error(w, "Only the player who opened the game may play it", http.StatusForbidden)
+		return nil, model.Deck{}, false
+	}
+
+	shoe, err := s.gameDeck(tenantOf(r), game.ID, game.DeckID)
+	if err != nil {
+		fail(w, err)
+		return nil, model.Deck{}, false
+	}
+	return game, shoe, true
+}
+
+func blackjackResponse(game *blackjack.Game, shoe model.Deck) BlackjackSerializer {
+	response := BlackjackSerializer{
+		ID:               game.ID,
+		Round:            game.Round,
+		Status:           game.Status,
+		ActiveHand:       game.Active,
+		DealerHitsSoft17: game.DealerHitsSoft17,
+		ShoeRemaining:    shoe.Remaining,
+		Hands:            []BlackjackHandSerializer{},
+	}
+	for _, hand := range game.Hands {
+		response.Hands = append(response.Hands, blackjackHand(hand))
+	}
+
+	dealer := game.Dealer
+	if game.Status == blackjack.StatusPlayerTurn && len(dealer.Cards) > 1 {
+		dealer.Cards = dealer.Cards[:1]
+	}
+	response.Dealer = blackjackHand(dealer)
+	return response
+}
+
+func blackjackHand(hand blackjack.Hand) BlackjackHandSerializer {
+	total, soft := hand.Value()
+	return BlackjackHandSerializer{
+		Cards:   hand.Cards,
+		Total:   total,
+		Soft:    soft,
+		Bet:     hand.Bet,
+		Doubled: hand.Doubled,
+		Split:   hand.Split,
+		Done:    hand.Done,
+		Result:  hand.Result,
+		Payout:  hand.Payout,
+	}
+}
@@ -0,0 +1,212 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	api "toggl-test-wiliam/api"
+	"toggl-test-wiliam/game/blackjack"
+	model "toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlackjack_PlayRound(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/blackjack?decks=2&bet=10", "application/json", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	game := api.BlackjackSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&game))
+	assert.NotEmpty(t, game.ID)
+	assert.Equal(t, 1, game.Round)
+	assert.Equal(t, 104-4, game.ShoeRemaining)
+	assert.Len(t, game.Hands, 1)
+	assert.Len(t, game.Hands[0].Cards, 2)
+	assert.Equal(t, 10, game.Hands[0].Bet)
+
+	// The hole card stays hidden while the player decides
+	if game.Status == blackjack.StatusPlayerTurn {
+		assert.Len(t, game.Dealer.Cards, 1)
+
+		resp, err = http.Get(testSuite.ts.URL + "/blackjack/" + game.ID)
+		assert.NoError(t, err)
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&game))
+		assert.Len(t, game.Dealer.Cards, 1)
+	}
+
+	for game.Status == blackjack.StatusPlayerTurn {
+		resp, err = http.Post(testSuite.ts.URL+"/blackjack/"+game.ID+"/stand", "application/json", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&game))
+	}
+	assert.Equal(t, blackjack.StatusFinished, game.Status)
+	assert.GreaterOrEqual(t, len(game.Dealer.Cards), 2)
+	assert.NotEmpty(t, game.Hands[0].Result)
+
+	// The round is over, so only a new deal is accepted
+	resp, _ = http.Post(testSuite.ts.URL+"/blackjack/"+game.ID+"/hit", "application/json", nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp, err = http.Post(testSuite.ts.URL+"/blackjack/"+game.ID+"/deal?bet=5", "application/json", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&game))
+	assert.Equal(t, 2, game.Round)
+	assert.Equal(t, 5, game.Hands[0].Bet)
+
+	testSuite.TearDownTest()
+}
+
+func TestBlackjack_InvalidRequests(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, _ := http.Post(testSuite.ts.URL+"/blackjack?bet=0", "application/json", nil)
+	resp_body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "bet must be a positive amount\n", string(resp_body))
+
+	resp, _ = http.Post(testSuite.ts.URL+"/blackjack?bet=10&decks=9", "application/json", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = http.Get(testSuite.ts.URL + "/blackjack/unknown")
+	resp_body, _ = ioutil.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "Game not found\n", string(resp_body))
+
+	testSuite.TearDownTest()
+}
+
+func TestBlackjack_ReshufflesPastCutCard(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+	// Playing 30 rounds takes more actions than a client may burst
+	testSuite.server.DrawLimit = api.RateLimit{}
+
+	resp, err := http.Post(testSuite.ts.URL+"/blackjack?decks=1&bet=10", "application/json", nil)
+	assert.NoError(t, err)
+	game := api.BlackjackSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&game))
+
+	// A single deck runs dry within a few rounds unless it is reshuffled
+	reshuffled := false
+	for round := 0; round < 30; round++ {
+		for game.Status == blackjack.StatusPlayerTurn {
+			resp, err = http.Post(testSuite.ts.URL+"/blackjack/"+game.ID+"/hit", "application/json", nil)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&game))
+		}
+
+		remaining := game.ShoeRemaining
+		resp, err = http.Post(testSuite.ts.URL+"/blackjack/"+game.ID+"/deal?bet=10", "application/json", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&game))
+		if remaining <= 13 {
+			assert.Equal(t, 52-4, game.ShoeRemaining)
+			reshuffled = true
+		}
+	}
+	assert.True(t, reshuffled)
+
+	testSuite.TearDownTest()
+}
+
+func TestBlackjack_ShoeIsHidden(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/blackjack?decks=1&bet=10", "application/json", nil)
+	assert.NoError(t, err)
+	game := api.BlackjackSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&game))
+
+	stored := blackjack.Game{}
+	testSuite.db.First(&stored, "id = ?", game.ID)
+
+	for _, path := range []string{"", "/history", "/draw", "/export"} {
+		resp, err = http.Get(testSuite.ts.URL + "/deck/" + stored.DeckID + path)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, path)
+	}
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck")
+	assert.NoError(t, err)
+	list := api.ListDecksSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	assert.Empty(t, list.Decks)
+
+	testSuite.TearDownTest()
+}
+
+func TestBlackjack_RefillsShoeMidRound(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	game := api.BlackjackSerializer{}
+	for game.Status != blackjack.StatusPlayerTurn {
+		resp, err := http.Post(testSuite.ts.URL+"/blackjack?decks=1&bet=10", "application/json", nil)
+		assert.NoError(t, err)
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&game))
+	}
+
+	// Empty the shoe behind the player's back, as if the round had dealt it all
+	stored := blackjack.Game{}
+	testSuite.db.First(&stored, "id = ?", game.ID)
+	testSuite.db.Model(&model.Deck{}).Where("id = ?", stored.DeckID).UpdateColumns(map[string]interface{}{"cards": "[]", "remaining": 0})
+
+	resp, err := http.Post(testSuite.ts.URL+"/blackjack/"+game.ID+"/hit", "application/json", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&game))
+	assert.Len(t, game.Hands[0].Cards, 3)
+
+	// The refilled shoe leaves out the cards still on the table
+	refilled := blackjack.Game{}
+	testSuite.db.First(&refilled, "id = ?", game.ID)
+	assert.NotEqual(t, stored.DeckID, refilled.DeckID)
+	assert.Equal(t, 52-len(refilled.Hands[0].Cards)-len(refilled.Dealer.Cards), game.ShoeRemaining)
+
+	// The emptied shoe is retired, leaving the game a single live deck
+	var live int64
+	testSuite.db.Model(&model.Deck{}).Where("game_id = ?", game.ID).Count(&live)
+	assert.Equal(t, int64(1), live)
+	retired := model.Deck{}
+	testSuite.db.Unscoped().First(&retired, "id = ?", stored.DeckID)
+	assert.True(t, retired.DeletedAt.Valid)
+
+	testSuite.TearDownTest()
+}
+
+func TestBlackjack_OnlyCreatorPlays(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+	testSuite.server.APIKeys = playerKeys
+
+	resp := asPlayer(t, "POST", testSuite.ts.URL+"/blackjack?decks=1&bet=10", "alice")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	game := api.BlackjackSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&game))
+	url := testSuite.ts.URL + "/blackjack/" + game.ID
+
+	for _, path := range []string{"/hit", "/stand", "/double", "/split", "/deal?bet=10"} {
+		resp = asPlayer(t, "POST", url+path, "bob")
+		resp_body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode, path)
+		assert.Equal(t, "Only the player who opened the game may play it\n", string(resp_body), path)
+	}
+	resp = asPlayer(t, "GET", url, "bob")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = asPlayer(t, "GET", url, "alice")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"toggl-test-wiliam/model"
+
+	"github.com/google/uuid"
+)
+
+// CloneDeck forks a deck into a new one holding the exact same remaining
+// cards in the same order, keeping its shuffled flag, owner, protection and
+// metadata. The caller becomes its owner through a new owner token. With
+// "include_piles" the piles come along too, with their owners and
+// visibility, as long as the caller may see every one of them
+func (s *Server) CloneDeck(w http.ResponseWriter, r *http.Request) {
+	source, ok := s.findDeck(w, r, model.RoleReadOnly)
+	if !ok || !visibleOnly(w, r, source) {
+		return
+	}
+
+	expiresAt, err := s.expiresAt(r.URL.Query().Get("ttl"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var piles []model.Pile
+	if includePiles, _ := strconv.ParseBool(r.URL.Query().Get("include_piles")); includePiles {
+		if err := s.DB.Where("deck_id = ?", source.ID).Order("id").Find(&piles).Error; err != nil {
+			s.Logger.Printf("list piles of deck %s: %v", source.ID, err)
+			http.Error(w, "database error", http.StatusInternalServerError)
+			return
+		}
+	}
+	for i, pile := range piles {
+		if !pile.VisibleTo(callerID(r)) {
+			http.Error(w, "Pile "+pile.Name+" is not visible to you", http.StatusForbidden)
+			return
+		}
+		piles[i] = model.Pile{Name: pile.Name, Owner: pile.Owner, Visibility: pile.Visibility, Cards: pile.Cards}
+	}
+
+	deck := model.Deck{
+		ID:         uuid.New().String(),
+		Owner:      source.Owner,
+		Visibility: source.Visibility,
+		Protected:  source.Protected,
+		Shuffled:   source.Shuffled,
+		Remaining:  len(source.Cards),
+		ExpiresAt:  expiresAt,
+		CardType:   source.CardType,
+		Metadata:   source.Metadata,
+		Cards:      append([]model.Card{}, source.Cards...),
+	}
+	stampCreator(r, &deck)
+	if owner := r.URL.Query().Get("owner"); owner != "" {
+		deck.Owner = owner
+	}
+
+	created := model.DeckEvent{
+		Type:       model.EventCreated,
+		Cards:      model.CardCodes(deck.Cards),
+		Shuffled:   deck.Shuffled,
+		ClonedFrom: source.ID,
+	}
+	if len(piles) > 0 {
+		created.Piles = map[string][]string{}
+		for _, pile := range piles {
+			created.Piles[pile.Name] = pile.Cards
+		}
+	}
+	token, err := s.insertDeck(&deck, piles, created)
+	if err != nil {
+		s.Logger.Printf("clone deck %s: %v", source.ID, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := CreateDeckSerializer{
+		ID:         deck.ID,
+		Shuffled:   deck.Shuffled,
+		Remaining:  len(deck.Cards),
+		Protected:  deck.Protected,
+		OwnerToken: token,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,135 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	api "toggl-test-wiliam/api"
+	model "toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloneDeck_KeepsOrderAndFlags(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck?shuffle=true&owner=alice", "application/json", nil)
+	assert.NoError(t, err)
+
+	source := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&source))
+
+	_, err = http.Get(testSuite.ts.URL + "/deck/" + source.ID + "/draw?count=5")
+	assert.NoError(t, err)
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + source.ID)
+	assert.NoError(t, err)
+
+	opened := api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&opened))
+
+	resp, err = http.Post(testSuite.ts.URL+"/deck/"+source.ID+"/clone", "application/json", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	clone := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&clone))
+	assert.NotEqual(t, source.ID, clone.ID)
+	assert.True(t, clone.Shuffled)
+	assert.Equal(t, 47, clone.Remaining)
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + clone.ID)
+	assert.NoError(t, err)
+
+	openedClone := api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&openedClone))
+	assert.Equal(t, opened.Cards, openedClone.Cards)
+
+	var stored model.Deck
+	testSuite.db.First(&stored, "id = ?", clone.ID)
+	assert.Equal(t, "alice", stored.Owner)
+
+	// Drawing from the clone leaves the source untouched
+	_, err = http.Get(testSuite.ts.URL + "/deck/" + clone.ID + "/draw?count=2")
+	assert.NoError(t, err)
+
+	testSuite.db.First(&stored, "id = ?", source.ID)
+	assert.Equal(t, 47, stored.Remaining)
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + clone.ID + "/history")
+	assert.NoError(t, err)
+
+	history := api.HistorySerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&history))
+	assert.Equal(t, model.EventCreated, history.Events[0].Type)
+	assert.Equal(t, source.ID, history.Events[0].ClonedFrom)
+	assert.True(t, history.Events[0].Shuffled)
+
+	testSuite.TearDownTest()
+}
+
+func TestCloneDeck_NotFound(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, _ := http.Post(testSuite.ts.URL+"/deck/zxczxczxc/clone", "application/json", nil)
+	resp_body, _ := ioutil.ReadAll(resp.Body)
+
+	assert.Equal(t, "Deck not found\n", string(resp_body))
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
+
+func TestCloneDeck_IncludePiles(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+	testSuite.server.APIKeys = playerKeys
+
+	resp := asPlayer(t, "POST", testSuite.ts.URL+"/deck?cards=2C,AS,KH,3D", "alice")
+	source := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&source))
+	url := testSuite.ts.URL + "/deck/" + source.ID
+	asPlayer(t, "POST", url+"/pile?name=hand&visibility=owner", "alice")
+	asPlayer(t, "POST", url+"/pile/hand/draw?count=2", "alice")
+	asPlayer(t, "POST", url+"/pile?name=discard", "alice")
+
+	// Without the flag only the remaining cards are cloned
+	resp = asPlayer(t, "POST", url+"/clone", "alice")
+	clone := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&clone))
+	resp = asPlayer(t, "GET", testSuite.ts.URL+"/deck/"+clone.ID+"/piles", "alice")
+	piles := api.ListPilesSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&piles))
+	assert.Empty(t, piles.Piles)
+
+	// bob may not see alice's hand, so he may not copy it either
+	resp = asPlayer(t, "POST", url+"/clone?include_piles=true", "bob")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = asPlayer(t, "POST", url+"/clone?include_piles=true", "alice")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	clone = api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&clone))
+	assert.Equal(t, 2, clone.Remaining)
+
+	resp = asPlayer(t, "GET", testSuite.ts.URL+"/deck/"+clone.ID+"/piles", "alice")
+	piles = api.ListPilesSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&piles))
+	assert.Len(t, piles.Piles, 2)
+	assert.Equal(t, "hand", piles.Piles[0].Name)
+	assert.Equal(t, "alice", piles.Piles[0].Owner)
+	assert.Equal(t, model.VisibilityOwner, piles.Piles[0].Visibility)
+	assert.Equal(t, []string{"KH", "3D"}, model.CardCodes(piles.Piles[0].Cards))
+	assert.Equal(t, "discard", piles.Piles[1].Name)
+	assert.Empty(t, piles.Piles[1].Cards)
+
+	resp = asPlayer(t, "GET", testSuite.ts.URL+"/deck/"+clone.ID+"/pile/hand", "bob")
+	hand := api.PileSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&hand))
+	assert.True(t, hand.Cards[0].Hidden)
+
+	testSuite.TearDownTest()
+}
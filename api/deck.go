@@ -1,135 +1,400 @@
 package api
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 	"toggl-test-wiliam/model"
 
 	"github.com/gorilla/mux"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 type CreateDeckSerializer struct {
-	ID        string `json:"deck_id"`
-	Shuffled  bool   `json:"shuffled"`
-	Remaining int    `json:"remaining"`
+	ID         string `json:"deck_id"`
+	Shuffled   bool   `json:"shuffled"`
+	Remaining  int    `json:"remaining"`
+	Protected  bool   `json:"protected,omitempty"`
+	OwnerToken string `json:"owner_token,omitempty"`
 }
 
 type OpenDeckSerializer struct {
-	ID        string       `json:"deck_id"`
-	Shuffled  bool         `json:"shuffled"`
-	Remaining int          `json:"remaining"`
-	Cards     []model.Card `json:"cards"`
+	ID        string          `json:"deck_id"`
+	Shuffled  bool            `json:"shuffled"`
+	Remaining int             `json:"remaining"`
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"`
+	EventNo   int             `json:"event_no,omitempty"`
+	CardType  string          `json:"card_type,omitempty"`
+	CreatedBy string          `json:"created_by,omitempty"`
+	Protected bool            `json:"protected,omitempty"`
+	SessionID string          `json:"session_id,omitempty"`
+	Metadata  json.RawMessage `json:"metadata,omitempty"`
+	Cards     []model.Card    `json:"cards"`
+	// Piles are only shown for a deck rebuilt at a given event
+	Piles []PileSerializer `json:"piles,omitempty"`
 }
 
-func connectDB() *gorm.DB {
-	db, err := gorm.Open(sqlite.Open("game.db"), &gorm.Config{})
-	if err != nil {
-		panic("failed to connect database")
-	}
-	return db
+type DeckSummarySerializer struct {
+	ID        string     `json:"deck_id"`
+	Owner     string     `json:"owner,omitempty"`
+	CreatedBy string     `json:"created_by,omitempty"`
+	Shuffled  bool       `json:"shuffled"`
+	Remaining int        `json:"remaining"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
-func CreateNewDeck(w http.ResponseWriter, r *http.Request) {
-	db, ok := r.Context().Value("db").(*gorm.DB)
-	if !ok {
-		http.Error(w, "database error", http.StatusInternalServerError)
+type ListDecksSerializer struct {
+	Decks      []DeckSummarySerializer `json:"decks"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+}
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+// CreateDeckRequest describes a new deck, either through the query parameters
+// of POST /deck or as its JSON body when the ordered card list is too long for a URL
+type CreateDeckRequest struct {
+	Cards      []string        `json:"cards"`
+	CardType   string          `json:"card_type"`
+	Shuffle    bool            `json:"shuffle"`
+	Shuffled   bool            `json:"shuffled"`
+	Owner      string          `json:"owner"`
+	Visibility string          `json:"visibility"`
+	Protected  bool            `json:"protected"`
+	TTL        string          `json:"ttl"`
+	Metadata   json.RawMessage `json:"metadata"`
+}
+
+// maxCreateDeckBody bounds the JSON body of a deck creation
+const maxCreateDeckBody = 1 << 20
+
+func (s *Server) CreateNewDeck(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxCreateDeckBody)
+	req, err := parseCreateDeckRequest(r)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	shuffleParam := r.URL.Query().Get("shuffle")
-	cardsParam := r.URL.Query().Get("cards")
+	deck, token, err := s.createDeck(callerOf(r), req)
+	if err != nil {
+		fail(w, err)
+		return
+	}
 
-	// Default to not shuffling
-	shuffle := false
-	var cards []string
+	w.Header().Set("Content-Type", "application/json")
+	response := CreateDeckSerializer{
+		ID:         deck.ID,
+		Shuffled:   deck.Shuffled,
+		Remaining:  len(deck.Cards),
+		Protected:  deck.Protected,
+		OwnerToken: token,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// createDeck builds the deck described by the request for the caller and
+// stores it, returning it with its owner token
+func (s *Server) createDeck(c caller, req CreateDeckRequest) (model.Deck, string, error) {
+	if req.CardType == "" {
+		req.CardType = "FRENCH"
+	}
+	if err := s.catalog(s.DB, c.Tenant).Where("card_type = ?", req.CardType).First(&model.Card{}).Error; err != nil {
+		return model.Deck{}, "", badRequest("unknown card type: " + req.CardType)
+	}
 
-	// Parse "cards" query parameter
-	if cardsParam != "" {
-		cards = strings.Split(cardsParam, ",")
-		validCards := getValidCards("FRENCH", cards, db)
+	cards := req.Cards
+	if len(cards) > 0 {
+		validCards := getValidCards(req.CardType, cards, s.catalog(s.DB, c.Tenant))
 		invalidCards := getInvalidCards(cards, validCards)
 
 		if len(invalidCards) > 0 {
-			http.Error(w, fmt.Sprintf("invalid cards: %v", invalidCards), http.StatusBadRequest)
-			return
+			return model.Deck{}, "", badRequest(fmt.Sprintf("invalid cards: %v", invalidCards))
 		}
 	} else {
-		db.Model(&model.Card{}).Where("card_type = ?", "FRENCH").Pluck("code", &cards)
+		s.catalog(s.DB, c.Tenant).Where("card_type = ?", req.CardType).Pluck("code", &cards)
 	}
 
-	// Parse "shuffle" query parameter
-	if shuffleParam != "" {
-		shuffle, _ = strconv.ParseBool(shuffleParam)
+	// Parse "ttl", falling back to the server default
+	expiresAt, err := s.expiresAt(req.TTL)
+	if err != nil {
+		return model.Deck{}, "", badRequest(err.Error())
 	}
 
-	var err error
-	deck := model.Deck{}
-	deck, err = deck.Create(cards)
+	visibility, err := model.ParseVisibility(req.Visibility)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		return model.Deck{}, "", badRequest(err.Error())
+	}
+	if err := c.canOwn(visibility); err != nil {
+		return model.Deck{}, "", err
+	}
+
+	deck, err := s.newDeck(c.Tenant, req.CardType, cards)
+	if err != nil {
+		return model.Deck{}, "", badRequest(err.Error())
+	}
+
+	c.stamp(&deck)
+	deck.Owner = req.Owner
+	if deck.Owner == "" {
+		deck.Owner = deck.CreatedBy
+	}
+	deck.Visibility = visibility
+	deck.Protected = req.Protected
+	deck.ExpiresAt = expiresAt
+	deck.Shuffled = req.Shuffled
+	deck.Metadata = req.Metadata
+
+	events := []model.DeckEvent{{Type: model.EventCreated, Cards: model.CardCodes(deck.Cards), Shuffled: deck.Shuffled}}
+	if req.Shuffle {
+		seed := s.shuffleSeed()
+		deck.ShuffleSeed(seed)
+		events = append(events, model.DeckEvent{Type: model.EventShuffled, Seed: seed})
+	}
+
+	token, err := s.insertDeck(&deck, nil, events...)
+	if err != nil {
+		s.Logger.Printf("create deck: %v", err)
+		return model.Deck{}, "", errDatabase
+	}
+	return deck, token, nil
+}
+
+// parseCreateDeckRequest reads the shuffle, cards, ttl, owner, visibility and
+// protected query parameters, then the JSON body when one is sent, whose
+// fields win over the parameters they repeat
+func parseCreateDeckRequest(r *http.Request) (CreateDeckRequest, error) {
+	req := CreateDeckRequest{}
+
+	query := r.URL.Query()
+	if cardsParam := query.Get("cards"); cardsParam != "" {
+		req.Cards = strings.Split(cardsParam, ",")
+	}
+
+	// Default to not shuffling
+	if shuffleParam := query.Get("shuffle"); shuffleParam != "" {
+		req.Shuffle, _ = strconv.ParseBool(shuffleParam)
+	}
+	req.Protected, _ = strconv.ParseBool(query.Get("protected"))
+
+	req.TTL = query.Get("ttl")
+	req.Owner = query.Get("owner")
+	req.Visibility = query.Get("visibility")
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return req, err
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return req, nil
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		return req, fmt.Errorf("invalid request body: %v", err)
+	}
+	if len(req.Metadata) > 0 {
+		var metadata map[string]interface{}
+		if err := json.Unmarshal(req.Metadata, &metadata); err != nil {
+			return req, errors.New("metadata must be a JSON object")
+		}
+	}
+	return req, nil
+}
+
+// ListDecks pages through decks ordered by creation time, narrowed down by
+// the owner, shuffled, remaining_lt and created_after query parameters
+func (s *Server) ListDecks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	tx := notInGame(inTenant(s.DB.Model(&model.Deck{}), tenantOf(r)))
+
+	if owner := query.Get("owner"); owner != "" {
+		tx = tx.Where("owner = ?", owner)
+	}
+
+	if shuffledParam := query.Get("shuffled"); shuffledParam != "" {
+		shuffled, err := strconv.ParseBool(shuffledParam)
+		if err != nil {
+			http.Error(w, "invalid shuffled: "+shuffledParam, http.StatusBadRequest)
+			return
+		}
+		tx = tx.Where("shuffled = ?", shuffled)
+	}
+
+	if remainingParam := query.Get("remaining_lt"); remainingParam != "" {
+		remaining, err := strconv.Atoi(remainingParam)
+		if err != nil {
+			http.Error(w, "invalid remaining_lt: "+remainingParam, http.StatusBadRequest)
+			return
+		}
+		tx = tx.Where("remaining < ?", remaining)
+	}
+
+	if createdParam := query.Get("created_after"); createdParam != "" {
+		createdAfter, err := time.Parse(time.RFC3339, createdParam)
+		if err != nil {
+			http.Error(w, "invalid created_after: "+createdParam, http.StatusBadRequest)
+			return
+		}
+		tx = tx.Where("created_at > ?", createdAfter)
+	}
+
+	limit := defaultListLimit
+	if limitParam := query.Get("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed < 1 || parsed > maxListLimit {
+			http.Error(w, "invalid limit: "+limitParam, http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	if cursorParam := query.Get("cursor"); cursorParam != "" {
+		createdAt, id, err := decodeCursor(cursorParam)
+		if err != nil {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+		tx = tx.Where("created_at > ? OR (created_at = ? AND id > ?)", createdAt, createdAt, id)
+	}
+
+	// Fetch one extra row to know whether another page follows
+	var decks []model.Deck
+	if err := tx.Order("created_at, id").Limit(limit + 1).Find(&decks).Error; err != nil {
+		s.Logger.Printf("list decks: %v", err)
+		http.Error(w, "database error", http.StatusInternalServerError)
 		return
 	}
 
-	if shuffle {
-		deck.Shuffle()
+	response := ListDecksSerializer{Decks: []DeckSummarySerializer{}}
+	if len(decks) > limit {
+		decks = decks[:limit]
+		last := decks[len(decks)-1]
+		response.NextCursor = encodeCursor(last.CreatedAt, last.ID)
 	}
 
-	db.Create(&deck)
-	w.Header().Set("Content-Type", "application/json")
-	response := CreateDeckSerializer{
-		ID:        deck.ID,
-		Shuffled:  deck.Shuffled,
-		Remaining: len(deck.Cards),
+	for _, deck := range decks {
+		response.Decks = append(response.Decks, DeckSummarySerializer{
+			ID:        deck.ID,
+			Owner:     deck.Owner,
+			CreatedBy: deck.CreatedBy,
+			Shuffled:  deck.Shuffled,
+			Remaining: deck.Remaining,
+			CreatedAt: deck.CreatedAt,
+			ExpiresAt: deck.ExpiresAt,
+		})
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-func OpenDeck(w http.ResponseWriter, r *http.Request) {
-	db, ok := r.Context().Value("db").(*gorm.DB)
+// OpenDeck shows the remaining cards of a deck, or with the "at" query
+// parameter the state it had right after the given event, optionally
+// narrowed down to the cards matching the suit, color and rank filters.
+// Callers the deck is not visible to only see face down placeholders
+func (s *Server) OpenDeck(w http.ResponseWriter, r *http.Request) {
+	deck, ok := s.findDeck(w, r, model.RoleReadOnly)
 	if !ok {
-		http.Error(w, "database error", http.StatusInternalServerError)
 		return
 	}
 
-	deck := model.Deck{}
-	deck_id := mux.Vars(r)["deck_id"]
+	options, err := parseCardOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	db.First(&deck, "id = ?", deck_id)
-	if deck.ID == "" {
-		http.Error(w, "Deck not found", http.StatusNotFound)
+	filter, err := parseCardFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Filtering would tell which cards a hidden deck still holds
+	visible := deck.VisibleTo(callerID(r))
+	if !visible && filter != (model.CardFilter{}) {
+		http.Error(w, deckHiddenMessage, http.StatusForbidden)
 		return
 	}
 
+	eventNo := 0
+	var piles []PileSerializer
+	if atParam := r.URL.Query().Get("at"); atParam != "" {
+		at, err := strconv.Atoi(atParam)
+		if err != nil || at < 1 {
+			http.Error(w, "invalid at: "+atParam, http.StatusBadRequest)
+			return
+		}
+
+		historical, ok := s.replayDeck(w, deck.ID, at)
+		if !ok {
+			return
+		}
+		deck.Cards = s.spellOut(deck, historical.Cards)
+		deck.Shuffled = historical.Shuffled
+		eventNo = at
+
+		if piles, ok = s.historicalPiles(w, r, deck, historical.Piles); !ok {
+			return
+		}
+	}
+
+	// Filtering narrows the listed cards down, the deck still holds all of them
+	cards := deck.Cards
+	if filter != (model.CardFilter{}) {
+		rules, _ := model.LookupRules("ace_high")
+		if options.rules != nil {
+			rules = *options.rules
+		}
+		cards = filter.Filter(cards, rules)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	response := OpenDeckSerializer{
 		ID:        deck.ID,
 		Shuffled:  deck.Shuffled,
 		Remaining: len(deck.Cards),
-		Cards:     deck.Cards,
+		ExpiresAt: deck.ExpiresAt,
+		EventNo:   eventNo,
+		CardType:  deck.CardType,
+		CreatedBy: deck.CreatedBy,
+		Protected: deck.Protected,
+		SessionID: deck.SessionID,
+		Metadata:  deck.Metadata,
+		Cards:     s.withExtras(cards, options),
+		Piles:     piles,
+	}
+	if !visible {
+		response.Cards = redact(cards)
 	}
 
 	json.NewEncoder(w).Encode(response)
 }
 
-func DrawCards(w http.ResponseWriter, r *http.Request) {
-	db, ok := r.Context().Value("db").(*gorm.DB)
-	if !ok {
-		http.Error(w, "database error", http.StatusInternalServerError)
+func (s *Server) DrawCards(w http.ResponseWriter, r *http.Request) {
+	deck, ok := s.findDeck(w, r, model.RoleDraw)
+	if !ok || !outsideSession(w, deck) {
 		return
 	}
 
-	deck := model.Deck{}
-	deck_id := mux.Vars(r)["deck_id"]
-
-	db.First(&deck, "id = ?", deck_id)
-	if deck.ID == "" {
-		http.Error(w, "Deck not found", http.StatusNotFound)
+	options, err := parseCardOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -139,26 +404,248 @@ func DrawCards(w http.ResponseWriter, r *http.Request) {
 		count = 1
 	}
 
-	if count > len(deck.Cards) {
-		http.Error(w, "Not enough cards in the deck", http.StatusBadRequest)
+	cards, err := s.drawCards(&deck, count)
+	if err != nil {
+		fail(w, err)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.withExtras(cards, options))
+}
+
+// drawCards takes count cards off the top of a deck that is not dealt by a
+// session and records the draw
+func (s *Server) drawCards(deck *model.Deck, count int) ([]model.Card, error) {
+	if err := sessionless(*deck); err != nil {
+		return nil, err
+	}
+	if count < 1 {
+		return nil, badRequest(fmt.Sprintf("invalid count: %d", count))
+	}
+	if count > len(deck.Cards) {
+		return nil, badRequest("Not enough cards in the deck")
+	}
+
 	cards, err := deck.Draw(count)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, badRequest(err.Error())
+	}
+
+	err = s.transaction(s.DB, func(tx *gorm.DB) error {
+		if err := tx.Save(deck).Error; err != nil {
+			return err
+		}
+		return s.recordEvents(tx, deck.ID, model.DeckEvent{
+			Type:  model.EventDrawn,
+			Count: count,
+			Cards: model.CardCodes(cards),
+		})
+	})
+	if err != nil {
+		s.Logger.Printf("save deck %s: %v", deck.ID, err)
+		return nil, errDatabase
+	}
+	return cards, nil
+}
+
+// ShuffleDeck shuffles the remaining cards of a deck, which undo can put
+// back in their previous order
+func (s *Server) ShuffleDeck(w http.ResponseWriter, r *http.Request) {
+	deck, ok := s.findDeck(w, r, model.RoleDealer)
+	if !ok {
 		return
 	}
 
-	db.Save(&deck)
+	if err := s.shuffleDeck(&deck); err != nil {
+		fail(w, err)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(cards)
+	response := OpenDeckSerializer{
+		ID:        deck.ID,
+		Shuffled:  deck.Shuffled,
+		Remaining: len(deck.Cards),
+		ExpiresAt: deck.ExpiresAt,
+		Cards:     deck.Cards,
+	}
+	if !deck.VisibleTo(callerID(r)) {
+		response.Cards = redact(deck.Cards)
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// shuffleDeck shuffles the remaining cards of a deck that is not dealt by a
+// session with a recorded seed, so that its history can replay it
+func (s *Server) shuffleDeck(deck *model.Deck) error {
+	if err := sessionless(*deck); err != nil {
+		return err
+	}
+
+	seed := s.shuffleSeed()
+	deck.ShuffleSeed(seed)
+
+	err := s.transaction(s.DB, func(tx *gorm.DB) error {
+		if err := tx.Save(deck).Error; err != nil {
+			return err
+		}
+		return s.recordEvents(tx, deck.ID, model.DeckEvent{Type: model.EventShuffled, Seed: seed})
+	})
+	if err != nil {
+		s.Logger.Printf("save deck %s: %v", deck.ID, err)
+		return errDatabase
+	}
+	return nil
+}
+
+// expiresAt turns a "ttl" query parameter into an expiry time, using the
+// server default when it is empty; a zero default means decks never expire
+func (s *Server) expiresAt(ttlParam string) (*time.Time, error) {
+	ttl := s.DefaultTTL
+	if ttlParam != "" {
+		parsed, err := time.ParseDuration(ttlParam)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid ttl: %s", ttlParam)
+		}
+		ttl = parsed
+	}
+
+	if ttl <= 0 {
+		return nil, nil
+	}
+	expiresAt := s.Now().UTC().Add(ttl)
+	return &expiresAt, nil
 }
 
-func getValidCards(card_type string, codes []string, db *gorm.DB) map[string]string {
+// insertDeck stores a new deck together with its piles and the events that
+// built it, and returns the owner token minted for it
+func (s *Server) insertDeck(deck *model.Deck, piles []model.Pile, events ...model.DeckEvent) (string, error) {
+	var token string
+	err := s.transaction(s.DB, func(tx *gorm.DB) error {
+		if err := tx.Create(deck).Error; err != nil {
+			return err
+		}
+		for i := range piles {
+			piles[i].DeckID = deck.ID
+			if err := tx.Create(&piles[i]).Error; err != nil {
+				return err
+			}
+		}
+		if err := s.recordEvents(tx, deck.ID, events...); err != nil {
+			return err
+		}
+
+		var err error
+		token, err = s.mintDeckToken(tx, deck.ID, model.RoleOwner)
+		return err
+	})
+	return token, err
+}
+
+// deckError is a failed deck operation to report to the caller, as an HTTP
+// status by the handlers and as the matching code by the gRPC service
+type deckError struct {
+	status     int
+	message    string
+	retryAfter time.Duration
+}
+
+func (e *deckError) Error() string {
+	return e.message
+}
+
+// errDatabase stands for a failed query, logged where it happened
+var errDatabase = &deckError{status: http.StatusInternalServerError, message: "database error"}
+
+func badRequest(message string) error {
+	return &deckError{status: http.StatusBadRequest, message: message}
+}
+
+// fail answers the request with the status of a deckError, or 500 for any
+// other error
+func fail(w http.ResponseWriter, err error) {
+	var failure *deckError
+	if !errors.As(err, &failure) {
+		failure = errDatabase
+	}
+
+	if failure.status == http.StatusTooManyRequests {
+		tooManyRequests(w, failure.retryAfter, failure.message)
+		return
+	}
+	http.Error(w, failure.message, failure.status)
+}
+
+// findDeck loads the deck named in the route, answering 404 for unknown
+// decks, 410 for expired ones and 401 or 403 when the caller's deck token
+// lacks the required role
+func (s *Server) findDeck(w http.ResponseWriter, r *http.Request, required string) (model.Deck, bool) {
+	deck, err := s.deckFor(callerOf(r), mux.Vars(r)["deck_id"], required)
+	if err != nil {
+		fail(w, err)
+		return deck, false
+	}
+	return deck, true
+}
+
+// deckFor is findDeck for a caller of any API
+func (s *Server) deckFor(c caller, deckID, required string) (model.Deck, error) {
+	deck, err := s.deckIn(c.Tenant, deckID)
+	if err != nil {
+		return deck, err
+	}
+	return deck, s.authorize(c, deck, required)
+}
+
+// loadDeck is findDeck for a deck ID that does not come from the route;
+// decks of other tenants are not found either
+func (s *Server) loadDeck(w http.ResponseWriter, r *http.Request, deck_id string) (model.Deck, bool) {
+	deck, err := s.deckIn(tenantOf(r), deck_id)
+	if err != nil {
+		fail(w, err)
+		return deck, false
+	}
+	return deck, true
+}
+
+// deckIn looks a deck of the tenant up, failing with 404 for unknown decks
+// and for those a game deals from, and 410 for expired ones
+func (s *Server) deckIn(tenant, deckID string) (model.Deck, error) {
+	return s.lookupDeck(notInGame(inTenant(s.DB, tenant)), deckID)
+}
+
+// gameDeck is deckIn for the decks a game deals from, which only the game
+// itself may find
+func (s *Server) gameDeck(tenant, gameID, deckID string) (model.Deck, error) {
+	return s.lookupDeck(inTenant(s.DB, tenant).Where("game_id = ?", gameID), deckID)
+}
+
+// notInGame leaves out the decks games deal from, whose order and draws stay
+// hidden behind the game
+func notInGame(tx *gorm.DB) *gorm.DB {
+	return tx.Where("COALESCE(game_id, '') = ''")
+}
+
+func (s *Server) lookupDeck(tx *gorm.DB, deckID string) (model.Deck, error) {
+	deck := model.Deck{}
+
+	tx.First(&deck, "id = ?", deckID)
+	if deck.ID == "" {
+		return deck, &deckError{status: http.StatusNotFound, message: "Deck not found"}
+	}
+
+	if deck.Expired(s.Now()) {
+		return deck, &deckError{status: http.StatusGone, message: "Deck expired"}
+	}
+
+	return deck, nil
+}
+
+func getValidCards(card_type string, codes []string, catalog *gorm.DB) map[string]string {
 	var validCards []string
-	db.Model(&model.Card{}).Where("card_type = ? AND code IN (?)", card_type, codes).Pluck("code", &validCards)
+	catalog.Where("card_type = ? AND code IN (?)", card_type, codes).Pluck("code", &validCards)
 
 	validCardsMap := make(map[string]string)
 	for _, card := range validCards {
@@ -177,3 +664,41 @@ func getInvalidCards(codes []string, validCards map[string]string) []string {
 	}
 	return invalidCards
 }
+
+// encodeCursor packs the sort key of the last listed deck into an opaque token
+func encodeCursor(createdAt time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(createdAt.Format(time.RFC3339Nano) + "|" + id))
+}
+
+func decodeCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+
+	createdAt, id, found := strings.Cut(string(raw), "|")
+	if !found {
+		return time.Time{}, "", fmt.Errorf("malformed cursor")
+	}
+
+	parsed, err := time.Parse(time.RFC3339Nano, createdAt)
+	return parsed, id, err
+}
+
+// drawFrom deals single cards off a deck for the game engines, collecting a
+// drawn event for each; exhausted is returned once the deck runs out
+func drawFrom(deck *model.Deck, exhausted error) (func() (model.Card, error), *[]model.DeckEvent) {
+	events := []model.DeckEvent{}
+	return func() (model.Card, error) {
+		if deck.Remaining == 0 {
+			return model.Card{}, exhausted
+		}
+
+		cards, err := deck.Draw(1)
+		if err != nil {
+			return model.Card{}, err
+		}
+		events = append(events, model.DeckEvent{Type: model.EventDrawn, Count: 1, Cards: model.CardCodes(cards)})
+		return cards[0], nil
+	}, &events
+}
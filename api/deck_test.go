@@ -1,20 +1,18 @@
 package api_test
 
 import (
-	"context"
 	"encoding/json"
-	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 	api "toggl-test-wiliam/api"
 	model "toggl-test-wiliam/model"
 	seeds "toggl-test-wiliam/seeds"
 
-	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"gorm.io/driver/sqlite"
@@ -23,8 +21,9 @@ import (
 
 type APITestSuite struct {
 	suite.Suite
-	db *gorm.DB
-	ts *httptest.Server
+	db     *gorm.DB
+	server *api.Server
+	ts     *httptest.Server
 }
 
 func (suite *APITestSuite) SetupTest() {
@@ -32,36 +31,27 @@ func (suite *APITestSuite) SetupTest() {
 	suite.db, err = gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
 	assert.NoError(suite.T(), err)
 
-	// Seeds the database with a full deck of cards
-	if err = suite.db.AutoMigrate(&model.Card{}, &model.Deck{}); err == nil && suite.db.Migrator().HasTable(&model.Card{}) {
-		if err := suite.db.First(&model.Card{}).Error; errors.Is(err, gorm.ErrRecordNotFound) {
-			seeds.FrenchCardDeck(suite.db)
-		}
-	}
-
-	dbMiddleware := func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Pass the database connection to the context
-			ctx := context.WithValue(r.Context(), "db", suite.db)
-			next.ServeHTTP(w, r.WithContext(ctx))
-		})
-	}
-
-	// create a test router and add handlers to it
-	r := mux.NewRouter()
-	r.Use(dbMiddleware)
+	// Migrates the schema and seeds the database with a full deck of cards
+	assert.NoError(suite.T(), seeds.Setup(suite.db))
 
-	r.HandleFunc("/deck", api.CreateNewDeck).Methods("POST")
-	r.HandleFunc("/deck/{deck_id}", api.OpenDeck).Methods("GET")
-	r.HandleFunc("/deck/{deck_id}/draw", api.DrawCards).Methods("GET")
-
-	suite.ts = httptest.NewServer(r)
+	suite.server = api.NewServer(suite.db)
+	suite.ts = httptest.NewServer(suite.server)
 }
 
 func (suite *APITestSuite) TearDownTest() {
 	// cleanup
 	suite.ts.Close()
 	suite.db.Exec("DROP TABLE IF EXISTS decks;")
+	suite.db.Exec("DROP TABLE IF EXISTS deck_events;")
+	suite.db.Exec("DROP TABLE IF EXISTS blackjack_games;")
+	suite.db.Exec("DROP TABLE IF EXISTS holdem_tables;")
+	suite.db.Exec("DROP TABLE IF EXISTS solitaire_games;")
+	suite.db.Exec("DROP TABLE IF EXISTS sessions;")
+	suite.db.Exec("DROP TABLE IF EXISTS piles;")
+	suite.db.Exec("DROP TABLE IF EXISTS deck_tokens;")
+	suite.db.Exec("DROP TABLE IF EXISTS tenants;")
+	suite.db.Exec("DROP TABLE IF EXISTS webhooks;")
+	suite.db.Exec("DROP TABLE IF EXISTS webhook_deliveries;")
 	suite.db.Exec("DROP TABLE IF EXISTS cards;")
 }
 
@@ -261,6 +251,161 @@ func TestCreateNewDeck_WithShuffledAndCardsParameter(t *testing.T) {
 	testSuite.TearDownTest()
 }
 
+func TestCreateNewDeck_WithTTLParameter(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	now := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	testSuite.server.Now = func() time.Time { return now }
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck?ttl=30m", "application/json", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	openedDeck := api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&openedDeck))
+	assert.True(t, now.Add(30*time.Minute).Equal(*openedDeck.ExpiresAt))
+
+	testSuite.TearDownTest()
+}
+
+func TestCreateNewDeck_WithInvalidTTLParameter(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, _ := http.Post(testSuite.ts.URL+"/deck?ttl=-5m", "application/json", nil)
+	resp_body, _ := ioutil.ReadAll(resp.Body)
+
+	assert.Equal(t, "invalid ttl: -5m\n", string(resp_body))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
+
+func TestOpenDeck_Expired(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	expiresAt := time.Now().Add(-time.Minute)
+	testSuite.db.Create(&model.Deck{
+		ID:        "test_deck_id",
+		Remaining: 1,
+		ExpiresAt: &expiresAt,
+		Cards:     []model.Card{{Value: "ACE", Suit: "CLUBS", Code: "AC"}},
+	})
+
+	for _, path := range []string{"/deck/test_deck_id", "/deck/test_deck_id/draw"} {
+		resp, _ := http.Get(testSuite.ts.URL + path)
+		resp_body, _ := ioutil.ReadAll(resp.Body)
+
+		assert.Equal(t, "Deck expired\n", string(resp_body))
+		assert.Equal(t, http.StatusGone, resp.StatusCode)
+	}
+
+	testSuite.TearDownTest()
+}
+
+func TestListDecks_WithFilters(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	for _, query := range []string{"owner=alice", "owner=alice&shuffle=true", "owner=bob&cards=AS,KS"} {
+		resp, err := http.Post(testSuite.ts.URL+"/deck?"+query, "application/json", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	resp, err := http.Get(testSuite.ts.URL + "/deck?owner=alice&shuffled=true")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	list := api.ListDecksSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	assert.Len(t, list.Decks, 1)
+	assert.Equal(t, "alice", list.Decks[0].Owner)
+	assert.True(t, list.Decks[0].Shuffled)
+	assert.Empty(t, list.NextCursor)
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck?remaining_lt=10")
+	assert.NoError(t, err)
+
+	list = api.ListDecksSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	assert.Len(t, list.Decks, 1)
+	assert.Equal(t, "bob", list.Decks[0].Owner)
+	assert.Equal(t, 2, list.Decks[0].Remaining)
+
+	testSuite.TearDownTest()
+}
+
+func TestListDecks_Pagination(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	created := map[string]bool{}
+	for i := 0; i < 5; i++ {
+		resp, err := http.Post(testSuite.ts.URL+"/deck", "application/json", nil)
+		assert.NoError(t, err)
+
+		deck := api.CreateDeckSerializer{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+		created[deck.ID] = true
+	}
+
+	listed := map[string]bool{}
+	pages := 0
+	cursor := ""
+	for {
+		resp, err := http.Get(testSuite.ts.URL + "/deck?limit=2&cursor=" + cursor)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		list := api.ListDecksSerializer{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+		for _, deck := range list.Decks {
+			listed[deck.ID] = true
+		}
+
+		pages++
+		if list.NextCursor == "" {
+			break
+		}
+		cursor = list.NextCursor
+	}
+
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, created, listed)
+
+	testSuite.TearDownTest()
+}
+
+func TestListDecks_InvalidParameters(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	for query, message := range map[string]string{
+		"remaining_lt=few":     "invalid remaining_lt: few\n",
+		"created_after=monday": "invalid created_after: monday\n",
+		"limit=0":              "invalid limit: 0\n",
+		"cursor=!!!":           "invalid cursor\n",
+	} {
+		resp, _ := http.Get(testSuite.ts.URL + "/deck?" + query)
+		resp_body, _ := ioutil.ReadAll(resp.Body)
+
+		assert.Equal(t, message, string(resp_body))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	}
+
+	testSuite.TearDownTest()
+}
+
 func TestOpenDeck_Failed(t *testing.T) {
 	testSuite := new(APITestSuite)
 	testSuite.SetupTest()
@@ -411,3 +556,92 @@ func TestDrawCards_WithCountParameter(t *testing.T) {
 	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&drawnCard))
 	assert.Equal(t, drawnCard, mockDeck.Cards[len(mockDeck.Cards)-drawCount:])
 }
+
+func TestCreateNewDeck_WithJSONBody(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	body := `{
+  "cards": ["KH", "2C", "10D", "AS"],
+  "card_type": "FRENCH",
+  "shuffled": true,
+  "owner": "alice",
+  "metadata": {"table": 7, "game": "rummy"}
+}`
+	resp, err := http.Post(testSuite.ts.URL+"/deck", "application/json", strings.NewReader(body))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+	assert.True(t, deck.Shuffled)
+	assert.Equal(t, 4, deck.Remaining)
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID)
+	assert.NoError(t, err)
+
+	openedDeck := api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&openedDeck))
+	assert.True(t, openedDeck.Shuffled)
+	assert.Equal(t, "FRENCH", openedDeck.CardType)
+	assert.JSONEq(t, `{"table": 7, "game": "rummy"}`, string(openedDeck.Metadata))
+	assert.Equal(t, []string{"KH", "2C", "10D", "AS"}, model.CardCodes(openedDeck.Cards))
+
+	var stored model.Deck
+	testSuite.db.First(&stored, "id = ?", deck.ID)
+	assert.Equal(t, "alice", stored.Owner)
+
+	testSuite.TearDownTest()
+}
+
+func TestCreateNewDeck_WithInvalidJSONBody(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	for body, message := range map[string]string{
+		`{"cards": ["AS", "ZZ"]}`:            "invalid cards: [ZZ]\n",
+		`{"card_type": "TAROT"}`:             "unknown card type: TAROT\n",
+		`{"cards": ["AS"], "metadata": [1]}`: "metadata must be a JSON object\n",
+	} {
+		resp, _ := http.Post(testSuite.ts.URL+"/deck", "application/json", strings.NewReader(body))
+		resp_body, _ := ioutil.ReadAll(resp.Body)
+
+		assert.Equal(t, message, string(resp_body))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	}
+
+	testSuite.TearDownTest()
+}
+
+func TestCreateNewDeck_WithJSONBodyAndParameters(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	// Parameters the body leaves out still apply, those it repeats are overridden
+	resp, err := http.Post(testSuite.ts.URL+"/deck?cards=AS,KS&ttl=1h&owner=bob", "application/json", strings.NewReader(`{"owner": "alice"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+	assert.Equal(t, 2, deck.Remaining)
+
+	var stored model.Deck
+	testSuite.db.First(&stored, "id = ?", deck.ID)
+	assert.Equal(t, "alice", stored.Owner)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *stored.ExpiresAt, time.Minute)
+
+	testSuite.TearDownTest()
+}
+
+func TestCreateNewDeck_WithOversizedBody(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	body := `{"metadata": {"padding": "` + strings.Repeat("x", 2<<20) + `"}}`
+	resp, err := http.Post(testSuite.ts.URL+"/deck", "application/json", strings.NewReader(body))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
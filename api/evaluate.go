@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"toggl-test-wiliam/evaluator"
+)
+
+// EvaluatePokerRequest holds either a single hand in Cards, or several Hands
+// sharing the community cards in Board
+type EvaluatePokerRequest struct {
+	Cards []string   `json:"cards"`
+	Hands [][]string `json:"hands"`
+	Board []string   `json:"board"`
+}
+
+type PokerHandSerializer struct {
+	Category string   `json:"category"`
+	Ranks    []int    `json:"ranks"`
+	Cards    []string `json:"cards"`
+}
+
+type EvaluatePokerSerializer struct {
+	Hands   []PokerHandSerializer `json:"hands"`
+	Winners []int                 `json:"winners"`
+}
+
+// EvaluatePoker ranks one or more poker hands and tells which of them win,
+// rejecting cards found twice across the hands and the board
+func (s *Server) EvaluatePoker(w http.ResponseWriter, r *http.Request) {
+	req := EvaluatePokerRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hands := req.Hands
+	if len(req.Cards) > 0 {
+		hands = append([][]string{req.Cards}, hands...)
+	}
+	if len(hands) == 0 {
+		http.Error(w, "no hands to evaluate", http.StatusBadRequest)
+		return
+	}
+
+	// The hands are dealt from one deck, so no card shows up twice among them
+	seen := map[string]bool{}
+	for _, codes := range append(hands, req.Board) {
+		for _, code := range codes {
+			if seen[code] {
+				http.Error(w, "duplicate card: "+code, http.StatusBadRequest)
+				return
+			}
+			seen[code] = true
+		}
+	}
+
+	evaluated := make([]evaluator.PokerHand, len(hands))
+	response := EvaluatePokerSerializer{}
+	for i, codes := range hands {
+		hand, err := evaluator.EvaluatePoker(append(append([]string{}, codes...), req.Board...))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		evaluated[i] = hand
+		response.Hands = append(response.Hands, PokerHandSerializer{
+			Category: hand.Category.String(),
+			Ranks:    hand.Ranks,
+			Cards:    hand.Cards,
+		})
+	}
+	response.Winners = evaluator.Winners(evaluated)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,60 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	api "toggl-test-wiliam/api"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluatePoker_Showdown(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	body := `{"hands": [["AH", "AD"], ["7C", "8C"]], "board": ["AS", "9C", "10C", "2D", "KH"]}`
+	resp, err := http.Post(testSuite.ts.URL+"/evaluate/poker", "application/json", strings.NewReader(body))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	result := api.EvaluatePokerSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Len(t, result.Hands, 2)
+	assert.Equal(t, "THREE_OF_A_KIND", result.Hands[0].Category)
+	assert.Equal(t, []int{14, 13, 10}, result.Hands[0].Ranks)
+	assert.Equal(t, "HIGH_CARD", result.Hands[1].Category)
+	assert.Equal(t, []int{0}, result.Winners)
+
+	testSuite.TearDownTest()
+}
+
+func TestEvaluatePoker_SingleHand(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	body := `{"cards": ["10D", "JD", "QD", "KD", "AD"]}`
+	resp, err := http.Post(testSuite.ts.URL+"/evaluate/poker", "application/json", strings.NewReader(body))
+	assert.NoError(t, err)
+
+	result := api.EvaluatePokerSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, "ROYAL_FLUSH", result.Hands[0].Category)
+
+	resp, _ = http.Post(testSuite.ts.URL+"/evaluate/poker", "application/json", strings.NewReader(`{"cards": ["AS", "AS", "KD", "QD", "JD"]}`))
+	resp_body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, "duplicate card: AS\n", string(resp_body))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	for _, body := range []string{
+		`{"hands": [["AS", "KS"], ["AS", "QD"]], "board": ["2C", "5D", "9H"]}`,
+		`{"hands": [["AS", "KS"], ["JD", "QD"]], "board": ["2C", "KS", "9H"]}`,
+	} {
+		resp, _ = http.Post(testSuite.ts.URL+"/evaluate/poker", "application/json", strings.NewReader(body))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, body)
+	}
+
+	testSuite.TearDownTest()
+}
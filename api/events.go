@@ -0,0 +1,230 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+	"toggl-test-wiliam/model"
+
+	"gorm.io/gorm"
+)
+
+// eventBuffer is how many events a subscriber may lag behind before it is
+// dropped, leaving its client to reconnect with the last event it got
+const eventBuffer = 64
+
+// heartbeatInterval keeps idle streams from being cut by proxies
+const heartbeatInterval = 15 * time.Second
+
+// eventHub fans the recorded events of each deck out to its subscribers
+type eventHub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan model.DeckEvent]bool
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subscribers: map[string]map[chan model.DeckEvent]bool{}}
+}
+
+// subscribe returns a channel of the deck's events, closed when the
+// subscriber falls too far behind or cancels
+func (h *eventHub) subscribe(deckID string) (<-chan model.DeckEvent, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	events := make(chan model.DeckEvent, eventBuffer)
+	if h.subscribers[deckID] == nil {
+		h.subscribers[deckID] = map[chan model.DeckEvent]bool{}
+	}
+	h.subscribers[deckID][events] = true
+
+	return events, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.drop(deckID, events)
+	}
+}
+
+func (h *eventHub) publish(events []model.DeckEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, event := range events {
+		for subscriber := range h.subscribers[event.DeckID] {
+			select {
+			case subscriber <- event:
+			default:
+				h.drop(event.DeckID, subscriber)
+			}
+		}
+	}
+}
+
+func (h *eventHub) drop(deckID string, subscriber chan model.DeckEvent) {
+	if !h.subscribers[deckID][subscriber] {
+		return
+	}
+
+	delete(h.subscribers[deckID], subscriber)
+	if len(h.subscribers[deckID]) == 0 {
+		delete(h.subscribers, deckID)
+	}
+	close(subscriber)
+}
+
+type pendingEventsKey struct{}
+
+// pendingEvents collects the events recorded during a transaction, to be
+// published once it commits
+type pendingEvents struct {
+	events []model.DeckEvent
+}
+
+// transaction runs fn in a transaction on db and publishes the events it
+// recorded after committing, to subscribers and webhooks; nested in another
+// one, the outer one publishes
+func (s *Server) transaction(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	if _, nested := db.Statement.Context.Value(pendingEventsKey{}).(*pendingEvents); nested {
+		return db.Transaction(fn)
+	}
+
+	pending := &pendingEvents{}
+	ctx := context.WithValue(db.Statement.Context, pendingEventsKey{}, pending)
+	if err := db.WithContext(ctx).Transaction(fn); err != nil {
+		return err
+	}
+	s.events.publish(pending.events)
+	s.notifyWebhooks(pending.events)
+	return nil
+}
+
+// DeckEvents streams the operations on a deck as Server-Sent Events while
+// they happen, named after the event type and numbered by its event_no.
+// Reconnecting clients resume after their "Last-Event-ID", and "since"
+// replays the log from that event number on; cards are left out as in the
+// history
+func (s *Server) DeckEvents(w http.ResponseWriter, r *http.Request) {
+	deck, ok := s.findDeck(w, r, model.RoleReadOnly)
+	if !ok {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	after := -1
+	if lastID := r.Header.Get("Last-Event-ID"); lastID != "" {
+		var err error
+		if after, err = strconv.Atoi(lastID); err != nil || after < 0 {
+			http.Error(w, "invalid Last-Event-ID: "+lastID, http.StatusBadRequest)
+			return
+		}
+	} else if sinceParam := r.URL.Query().Get("since"); sinceParam != "" {
+		since, err := strconv.Atoi(sinceParam)
+		if err != nil || since < 1 {
+			http.Error(w, "invalid since: "+sinceParam, http.StatusBadRequest)
+			return
+		}
+		after = since - 1
+	}
+
+	// Subscribing before reading the backlog leaves no gap between the two
+	live, cancel := s.events.subscribe(deck.ID)
+	defer cancel()
+
+	var backlog []model.DeckEvent
+	if after >= 0 {
+		err := s.DB.Where("deck_id = ? AND seq > ?", deck.ID, after).Order("seq").Find(&backlog).Error
+		if err != nil {
+			s.Logger.Printf("load history of deck %s: %v", deck.ID, err)
+			http.Error(w, "database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	stream := eventStream{s: s, w: w, deck: deck, caller: callerID(r), last: after}
+	for _, event := range backlog {
+		if !stream.send(event) {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case event, open := <-live:
+			if !open || !stream.send(event) {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// eventStream writes the events of one deck to one client, once each
+type eventStream struct {
+	s      *Server
+	w      http.ResponseWriter
+	deck   model.Deck
+	caller string
+	last   int
+	hidden map[string]bool
+}
+
+func (e *eventStream) send(event model.DeckEvent) bool {
+	if event.Seq <= e.last {
+		return true
+	}
+	e.last = event.Seq
+
+	if e.unknownPile(event) {
+		hidden, err := e.s.hiddenPiles(e.deck.ID, e.caller)
+		if err != nil {
+			e.s.Logger.Printf("list piles of deck %s: %v", e.deck.ID, err)
+			return false
+		}
+		e.hidden = hidden
+	}
+	event = redactEvent(event, e.deck.VisibleTo(e.caller), e.hidden)
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		e.s.Logger.Printf("encode event %d of deck %s: %v", event.Seq, e.deck.ID, err)
+		return false
+	}
+	_, err = fmt.Fprintf(e.w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Type, data)
+	return err == nil
+}
+
+// unknownPile reports whether the event names a pile added since the
+// visibility of the piles was last looked up
+func (e *eventStream) unknownPile(event model.DeckEvent) bool {
+	if _, known := e.hidden[event.Pile]; event.Pile != "" && !known {
+		return true
+	}
+	for name := range event.Piles {
+		if _, known := e.hidden[name]; !known {
+			return true
+		}
+	}
+	return false
+}
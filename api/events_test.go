@@ -0,0 +1,136 @@
+package api_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	api "toggl-test-wiliam/api"
+	model "toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sseEvent struct {
+	ID    string
+	Name  string
+	Event model.DeckEvent
+}
+
+// openEvents subscribes to a deck and returns its events as they are read
+func openEvents(t *testing.T, url, lastEventID string) (<-chan sseEvent, func()) {
+	req, err := http.NewRequest("GET", url, nil)
+	require.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := make(chan sseEvent)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		current := sseEvent{}
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				current.ID = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				current.Name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &current.Event)
+			case line == "" && current.ID != "":
+				events <- current
+				current = sseEvent{}
+			}
+		}
+	}()
+	return events, func() { resp.Body.Close() }
+}
+
+func nextEvent(t *testing.T, events <-chan sseEvent) sseEvent {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+		return sseEvent{}
+	}
+}
+
+func TestDeckEvents_Stream(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck?cards=AS,KD,QH,JC&shuffle=true", "application/json", nil)
+	require.NoError(t, err)
+	deck := api.CreateDeckSerializer{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+	url := testSuite.ts.URL + "/deck/" + deck.ID
+
+	events, stop := openEvents(t, url+"/events?since=1", "")
+
+	created := nextEvent(t, events)
+	assert.Equal(t, "1", created.ID)
+	assert.Equal(t, model.EventCreated, created.Name)
+	assert.Equal(t, model.EventShuffled, nextEvent(t, events).Name)
+
+	resp, err = http.Get(url + "/draw?count=2")
+	require.NoError(t, err)
+	var drawn []model.Card
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&drawn))
+
+	event := nextEvent(t, events)
+	assert.Equal(t, "3", event.ID)
+	assert.Equal(t, model.EventDrawn, event.Name)
+	assert.Equal(t, 2, event.Event.Count)
+	assert.Equal(t, model.CardCodes(drawn), event.Event.Cards)
+
+	// Draws onto a pile the subscriber may not see keep their cards hidden
+	testSuite.server.APIKeys = playerKeys
+	asPlayer(t, "POST", url+"/pile?name=hand&visibility=owner", "alice")
+	asPlayer(t, "POST", url+"/pile/hand/draw", "alice")
+
+	event = nextEvent(t, events)
+	assert.Equal(t, "hand", event.Event.Pile)
+	assert.Empty(t, event.Event.Cards)
+
+	stop()
+	testSuite.TearDownTest()
+}
+
+func TestDeckEvents_Resume(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck?cards=AS,KD,QH", "application/json", nil)
+	require.NoError(t, err)
+	deck := api.CreateDeckSerializer{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+	url := testSuite.ts.URL + "/deck/" + deck.ID
+
+	http.Get(url + "/draw")
+	http.Post(url+"/undo", "application/json", nil)
+
+	// A reconnecting client only gets what it missed
+	events, stop := openEvents(t, url+"/events", "2")
+
+	event := nextEvent(t, events)
+	assert.Equal(t, "3", event.ID)
+	assert.Equal(t, model.EventUndone, event.Name)
+	assert.Equal(t, 2, event.Event.Undoes)
+
+	resp, err = http.Get(url + "/events?since=0")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	stop()
+	testSuite.TearDownTest()
+}
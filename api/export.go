@@ -0,0 +1,445 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"toggl-test-wiliam/model"
+)
+
+// DeckSnapshot is the portable form of a deck used by export and import
+type DeckSnapshot struct {
+	DeckID     string          `json:"deck_id"`
+	CardType   string          `json:"card_type"`
+	Shuffled   bool            `json:"shuffled"`
+	Seed       int64           `json:"seed,omitempty"`
+	EventNo    int             `json:"event_no"`
+	Owner      string          `json:"owner,omitempty"`
+	Visibility string          `json:"visibility,omitempty"`
+	Metadata   json.RawMessage `json:"metadata,omitempty"`
+	Cards      []string        `json:"cards"`
+	Piles      []PileSnapshot  `json:"piles,omitempty"`
+}
+
+// PileSnapshot is a pile of a deck snapshot, with its cards in order
+type PileSnapshot struct {
+	Name       string   `json:"name"`
+	Owner      string   `json:"owner,omitempty"`
+	Visibility string   `json:"visibility"`
+	Cards      []string `json:"cards"`
+}
+
+// The cards of a csv snapshot belong to the deck unless their row names a
+// pile; a pile without cards gets a single row without code
+var csvHeader = []string{"deck_id", "card_type", "shuffled", "seed", "event_no", "owner", "visibility", "pile", "pile_owner", "pile_visibility", "position", "code"}
+
+var exportContentTypes = map[string]string{
+	"json": "application/json",
+	"csv":  "text/csv",
+	"text": "text/plain; charset=utf-8",
+}
+
+// ExportDeck writes a snapshot of the deck and its piles as json, csv or text,
+// pointing at the last event of its history and the seed of its latest
+// shuffle. Like the deck, every pile has to be visible to the caller
+func (s *Server) ExportDeck(w http.ResponseWriter, r *http.Request) {
+	deck, ok := s.findDeck(w, r, model.RoleReadOnly)
+	if !ok || !visibleOnly(w, r, deck) {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		http.Error(w, "invalid format: "+format, http.StatusBadRequest)
+		return
+	}
+
+	events, err := model.Events(s.DB, deck.ID)
+	if err != nil {
+		s.Logger.Printf("load history of deck %s: %v", deck.ID, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	var piles []model.Pile
+	if err := s.DB.Where("deck_id = ?", deck.ID).Order("id").Find(&piles).Error; err != nil {
+		s.Logger.Printf("list piles of deck %s: %v", deck.ID, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	snapshot := DeckSnapshot{
+		DeckID:     deck.ID,
+		CardType:   deck.CardType,
+		Shuffled:   deck.Shuffled,
+		Owner:      deck.Owner,
+		Visibility: deck.Visibility,
+		Metadata:   deck.Metadata,
+		Cards:      model.CardCodes(deck.Cards),
+	}
+	for _, pile := range piles {
+		if !pile.VisibleTo(callerID(r)) {
+			http.Error(w, "Pile "+pile.Name+" is not visible to you", http.StatusForbidden)
+			return
+		}
+		snapshot.Piles = append(snapshot.Piles, PileSnapshot{Name: pile.Name, Owner: pile.Owner, Visibility: pile.Visibility, Cards: pile.Cards})
+	}
+	// Shuffles and imports carry seeds, the latest one is kept
+	for _, event := range events {
+		snapshot.EventNo = event.Seq
+		if event.Seed != 0 {
+			snapshot.Seed = event.Seed
+		}
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "json":
+		err = json.NewEncoder(&buf).Encode(snapshot)
+	case "csv":
+		err = writeSnapshotCSV(&buf, snapshot)
+	case "text":
+		err = writeSnapshotText(&buf, snapshot)
+	}
+	if err != nil {
+		s.Logger.Printf("export deck %s: %v", deck.ID, err)
+		http.Error(w, "export error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"deck-%s.%s\"", deck.ID, format))
+	w.Write(buf.Bytes())
+}
+
+// ImportDeck creates a new deck with its piles from a snapshot produced by
+// ExportDeck, in the format given by the "format" query parameter. The seed
+// of the snapshot is kept on the first history entry, to export it again.
+// A snapshot with piles may have dealt every card of its deck into them
+func (s *Server) ImportDeck(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxCreateDeckBody)
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+
+	var snapshot DeckSnapshot
+	var err error
+	switch format {
+	case "json":
+		err = json.NewDecoder(r.Body).Decode(&snapshot)
+	case "csv":
+		snapshot, err = readSnapshotCSV(r.Body)
+	case "text":
+		snapshot, err = readSnapshotText(r.Body)
+	default:
+		http.Error(w, "invalid format: "+format, http.StatusBadRequest)
+		return
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	} else if err != nil {
+		http.Error(w, "invalid snapshot: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if snapshot.CardType == "" {
+		snapshot.CardType = "FRENCH"
+	}
+	codes := append([]string{}, snapshot.Cards...)
+	for _, pile := range snapshot.Piles {
+		codes = append(codes, pile.Cards...)
+	}
+	invalidCards := getInvalidCards(codes, getValidCards(snapshot.CardType, codes, s.catalog(s.DB, tenantOf(r))))
+	if len(invalidCards) > 0 {
+		http.Error(w, fmt.Sprintf("invalid cards: %v", invalidCards), http.StatusBadRequest)
+		return
+	}
+
+	visibility, err := model.ParseVisibility(snapshot.Visibility)
+	if err != nil {
+		http.Error(w, "invalid snapshot: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	piles, err := snapshotPiles(snapshot)
+	if err != nil {
+		http.Error(w, "invalid snapshot: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := callerOf(r).canOwn(visibility); err != nil {
+		fail(w, err)
+		return
+	}
+	for _, pile := range piles {
+		if err := callerOf(r).canOwn(pile.Visibility); err != nil {
+			fail(w, err)
+			return
+		}
+	}
+
+	expiresAt, err := s.expiresAt(r.URL.Query().Get("ttl"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The cards of the piles count towards the size of the deck, which only
+	// keeps the remaining ones
+	deck, err := s.newDeck(tenantOf(r), snapshot.CardType, codes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	deck.Cards = deck.Cards[:len(snapshot.Cards)]
+	deck.Remaining = len(deck.Cards)
+
+	deck.Owner = snapshot.Owner
+	if owner := r.URL.Query().Get("owner"); owner != "" {
+		deck.Owner = owner
+	}
+	stampCreator(r, &deck)
+	deck.Visibility = visibility
+	deck.Protected, _ = strconv.ParseBool(r.URL.Query().Get("protected"))
+	deck.Shuffled = snapshot.Shuffled
+	deck.Metadata = snapshot.Metadata
+	deck.ExpiresAt = expiresAt
+
+	created := model.DeckEvent{
+		Type:     model.EventCreated,
+		Cards:    snapshot.Cards,
+		Shuffled: snapshot.Shuffled,
+		Seed:     snapshot.Seed,
+	}
+	if snapshot.DeckID != "" {
+		created.ImportedFrom = fmt.Sprintf("%s@%d", snapshot.DeckID, snapshot.EventNo)
+	}
+	if len(piles) > 0 {
+		created.Piles = map[string][]string{}
+		for _, pile := range piles {
+			created.Piles[pile.Name] = pile.Cards
+		}
+	}
+	token, err := s.insertDeck(&deck, piles, created)
+	if err != nil {
+		s.Logger.Printf("import deck: %v", err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := CreateDeckSerializer{
+		ID:         deck.ID,
+		Shuffled:   deck.Shuffled,
+		Remaining:  len(deck.Cards),
+		Protected:  deck.Protected,
+		OwnerToken: token,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// snapshotPiles checks the piles of a snapshot, which must have distinct names
+func snapshotPiles(snapshot DeckSnapshot) ([]model.Pile, error) {
+	var piles []model.Pile
+	names := map[string]bool{}
+	for _, pile := range snapshot.Piles {
+		if pile.Name == "" || names[pile.Name] {
+			return nil, fmt.Errorf("invalid pile name %q", pile.Name)
+		}
+		names[pile.Name] = true
+
+		visibility, err := model.ParseVisibility(pile.Visibility)
+		if err != nil {
+			return nil, err
+		}
+		cards := pile.Cards
+		if cards == nil {
+			cards = []string{}
+		}
+		piles = append(piles, model.Pile{Name: pile.Name, Owner: pile.Owner, Visibility: visibility, Cards: cards})
+	}
+	return piles, nil
+}
+
+// writeSnapshotCSV writes one row per card, repeating the deck attributes on
+// each row so that the file stays a plain table
+func writeSnapshotCSV(w io.Writer, snapshot DeckSnapshot) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	row := func(pile PileSnapshot, position int, code string) error {
+		return writer.Write([]string{
+			snapshot.DeckID,
+			snapshot.CardType,
+			strconv.FormatBool(snapshot.Shuffled),
+			strconv.FormatInt(snapshot.Seed, 10),
+			strconv.Itoa(snapshot.EventNo),
+			snapshot.Owner,
+			snapshot.Visibility,
+			pile.Name,
+			pile.Owner,
+			pile.Visibility,
+			strconv.Itoa(position),
+			code,
+		})
+	}
+
+	for i, code := range snapshot.Cards {
+		if err := row(PileSnapshot{}, i+1, code); err != nil {
+			return err
+		}
+	}
+	for _, pile := range snapshot.Piles {
+		if len(pile.Cards) == 0 {
+			if err := row(pile, 0, ""); err != nil {
+				return err
+			}
+		}
+		for i, code := range pile.Cards {
+			if err := row(pile, i+1, code); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func readSnapshotCSV(r io.Reader) (DeckSnapshot, error) {
+	snapshot := DeckSnapshot{}
+
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return snapshot, err
+	}
+	if len(rows) == 0 || strings.Join(rows[0], ",") != strings.Join(csvHeader, ",") {
+		return snapshot, errors.New("missing csv header")
+	}
+
+	for _, row := range rows[1:] {
+		snapshot.DeckID = row[0]
+		snapshot.CardType = row[1]
+		if snapshot.Shuffled, err = strconv.ParseBool(row[2]); err != nil {
+			return snapshot, err
+		}
+		if snapshot.Seed, err = strconv.ParseInt(row[3], 10, 64); err != nil {
+			return snapshot, err
+		}
+		if snapshot.EventNo, err = strconv.Atoi(row[4]); err != nil {
+			return snapshot, err
+		}
+		snapshot.Owner = row[5]
+		snapshot.Visibility = row[6]
+
+		if row[7] == "" {
+			snapshot.Cards = append(snapshot.Cards, row[11])
+			continue
+		}
+		if len(snapshot.Piles) == 0 || snapshot.Piles[len(snapshot.Piles)-1].Name != row[7] {
+			snapshot.Piles = append(snapshot.Piles, PileSnapshot{Name: row[7], Owner: row[8], Visibility: row[9], Cards: []string{}})
+		}
+		if row[11] != "" {
+			pile := &snapshot.Piles[len(snapshot.Piles)-1]
+			pile.Cards = append(pile.Cards, row[11])
+		}
+	}
+	return snapshot, nil
+}
+
+// writeSnapshotText writes a "key: value" line per attribute, with the cards
+// space separated from the bottom of the deck to the top, and a line per pile
+// holding its name, visibility, owner ("-" for none) and cards
+func writeSnapshotText(w io.Writer, snapshot DeckSnapshot) error {
+	_, err := fmt.Fprintf(w,
+		"deck_id: %s\ncard_type: %s\nshuffled: %t\nseed: %d\nevent_no: %d\nowner: %s\nvisibility: %s\ncards: %s\n",
+		snapshot.DeckID,
+		snapshot.CardType,
+		snapshot.Shuffled,
+		snapshot.Seed,
+		snapshot.EventNo,
+		snapshot.Owner,
+		snapshot.Visibility,
+		strings.Join(snapshot.Cards, " "),
+	)
+	for _, pile := range snapshot.Piles {
+		if err != nil {
+			return err
+		}
+		owner := pile.Owner
+		if owner == "" {
+			owner = "-"
+		}
+		_, err = fmt.Fprintf(w, "pile: %s\n", strings.Join(append([]string{pile.Name, pile.Visibility, owner}, pile.Cards...), " "))
+	}
+	return err
+}
+
+func readSnapshotText(r io.Reader) (DeckSnapshot, error) {
+	snapshot := DeckSnapshot{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			return snapshot, fmt.Errorf("malformed line %q", line)
+		}
+		value = strings.TrimSpace(value)
+
+		var err error
+		switch key {
+		case "deck_id":
+			snapshot.DeckID = value
+		case "card_type":
+			snapshot.CardType = value
+		case "shuffled":
+			snapshot.Shuffled, err = strconv.ParseBool(value)
+		case "seed":
+			snapshot.Seed, err = strconv.ParseInt(value, 10, 64)
+		case "event_no":
+			snapshot.EventNo, err = strconv.Atoi(value)
+		case "owner":
+			snapshot.Owner = value
+		case "visibility":
+			snapshot.Visibility = value
+		case "cards":
+			snapshot.Cards = strings.Fields(value)
+		case "pile":
+			fields := strings.Fields(value)
+			if len(fields) < 3 {
+				return snapshot, fmt.Errorf("malformed pile %q", value)
+			}
+			pile := PileSnapshot{Name: fields[0], Visibility: fields[1], Owner: fields[2], Cards: fields[3:]}
+			if pile.Owner == "-" {
+				pile.Owner = ""
+			}
+			snapshot.Piles = append(snapshot.Piles, pile)
+		default:
+			err = fmt.Errorf("unknown key %q", key)
+		}
+		if err != nil {
+			return snapshot, err
+		}
+	}
+	return snapshot, scanner.Err()
+}
@@ -0,0 +1,213 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	api "toggl-test-wiliam/api"
+	model "toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportDeck_RoundTrip(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck", "application/json",
+		strings.NewReader(`{"cards": ["AS", "KH", "10D", "2C"], "owner": "alice", "shuffle": true, "metadata": {"table": 7}}`))
+	assert.NoError(t, err)
+
+	source := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&source))
+
+	_, err = http.Get(testSuite.ts.URL + "/deck/" + source.ID + "/draw")
+	assert.NoError(t, err)
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + source.ID)
+	assert.NoError(t, err)
+
+	opened := api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&opened))
+
+	for format, contentType := range map[string]string{
+		"json": "application/json",
+		"csv":  "text/csv",
+		"text": "text/plain; charset=utf-8",
+	} {
+		resp, err = http.Get(testSuite.ts.URL + "/deck/" + source.ID + "/export?format=" + format)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, contentType, resp.Header.Get("Content-Type"))
+
+		resp, err = http.Post(testSuite.ts.URL+"/deck/import?format="+format, contentType, resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, format)
+
+		imported := api.CreateDeckSerializer{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&imported))
+		assert.NotEqual(t, source.ID, imported.ID)
+		assert.True(t, imported.Shuffled)
+		assert.Equal(t, 3, imported.Remaining)
+
+		resp, err = http.Get(testSuite.ts.URL + "/deck/" + imported.ID)
+		assert.NoError(t, err)
+
+		openedImport := api.OpenDeckSerializer{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&openedImport))
+		assert.Equal(t, opened.Cards, openedImport.Cards)
+
+		var stored model.Deck
+		testSuite.db.First(&stored, "id = ?", imported.ID)
+		assert.Equal(t, "alice", stored.Owner)
+
+		var created model.DeckEvent
+		testSuite.db.First(&created, "deck_id = ? AND seq = 1", imported.ID)
+		assert.Equal(t, source.ID+"@3", created.ImportedFrom)
+	}
+
+	testSuite.TearDownTest()
+}
+
+func TestExportDeck_Text(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck?cards=AS,KH&owner=bob", "application/json", nil)
+	assert.NoError(t, err)
+
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "/export?format=text")
+	assert.NoError(t, err)
+	resp_body, _ := ioutil.ReadAll(resp.Body)
+
+	expected := "deck_id: " + deck.ID + "\n" +
+		"card_type: FRENCH\n" +
+		"shuffled: false\n" +
+		"seed: 0\n" +
+		"event_no: 1\n" +
+		"owner: bob\n" +
+		"visibility: PUBLIC\n" +
+		"cards: AS KH\n"
+	assert.Equal(t, expected, string(resp_body))
+
+	testSuite.TearDownTest()
+}
+
+func TestImportDeck_Invalid(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	for query, body := range map[string]string{
+		"format=json": `{"cards": ["AS", "XX"]}`,
+		"format=csv":  "code\nAS\n",
+		"format=text": "cards AS KH\n",
+		"format=yaml": "cards: [AS]\n",
+	} {
+		resp, _ := http.Post(testSuite.ts.URL+"/deck/import?"+query, "text/plain", strings.NewReader(body))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+	}
+
+	testSuite.TearDownTest()
+}
+
+func TestExportDeck_RoundTripPilesAndSeed(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck?cards=AS,KH,10D,2C&shuffle=true", "application/json", nil)
+	assert.NoError(t, err)
+	source := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&source))
+
+	_, err = http.Post(testSuite.ts.URL+"/deck/"+source.ID+"/pile?name=discard&owner=alice", "application/json", nil)
+	assert.NoError(t, err)
+	_, err = http.Post(testSuite.ts.URL+"/deck/"+source.ID+"/pile?name=spare", "application/json", nil)
+	assert.NoError(t, err)
+	_, err = http.Post(testSuite.ts.URL+"/deck/"+source.ID+"/pile/discard/draw?count=2", "application/json", nil)
+	assert.NoError(t, err)
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + source.ID + "/export")
+	assert.NoError(t, err)
+	exported := api.DeckSnapshot{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&exported))
+	assert.NotZero(t, exported.Seed)
+	assert.Len(t, exported.Piles, 2)
+
+	for format, contentType := range map[string]string{
+		"json": "application/json",
+		"csv":  "text/csv",
+		"text": "text/plain; charset=utf-8",
+	} {
+		resp, err = http.Get(testSuite.ts.URL + "/deck/" + source.ID + "/export?format=" + format)
+		assert.NoError(t, err)
+
+		resp, err = http.Post(testSuite.ts.URL+"/deck/import?format="+format, contentType, resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, format)
+		imported := api.CreateDeckSerializer{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&imported))
+
+		resp, err = http.Get(testSuite.ts.URL + "/deck/" + imported.ID + "/export")
+		assert.NoError(t, err)
+		reexported := api.DeckSnapshot{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&reexported))
+		assert.Equal(t, exported.Seed, reexported.Seed, format)
+		assert.Equal(t, exported.Cards, reexported.Cards, format)
+		assert.Equal(t, exported.Piles, reexported.Piles, format)
+	}
+
+	testSuite.TearDownTest()
+}
+
+func TestExportDeck_RoundTripVisibilityAndDealtDecks(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+	testSuite.server.APIKeys = playerKeys
+
+	resp := asPlayer(t, "POST", testSuite.ts.URL+"/deck?cards=AS,KH&visibility=owner", "alice")
+	source := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&source))
+	url := testSuite.ts.URL + "/deck/" + source.ID
+	asPlayer(t, "POST", url+"/pile?name=hand", "alice")
+	asPlayer(t, "POST", url+"/pile/hand/draw?count=2", "alice")
+
+	for _, format := range []string{"json", "csv", "text"} {
+		resp = asPlayer(t, "GET", url+"/export?format="+format, "alice")
+		assert.Equal(t, http.StatusOK, resp.StatusCode, format)
+		req, _ := http.NewRequest("POST", testSuite.ts.URL+"/deck/import?format="+format, resp.Body)
+		req.Header.Set("X-API-Key", "alice-key")
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, format)
+		imported := api.CreateDeckSerializer{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&imported))
+		assert.Equal(t, 0, imported.Remaining)
+
+		// Every card is still in the hand, and the deck stays alice's to see
+		var stored model.Deck
+		testSuite.db.First(&stored, "id = ?", imported.ID)
+		assert.Equal(t, model.VisibilityOwner, stored.Visibility, format)
+
+		resp = asPlayer(t, "GET", testSuite.ts.URL+"/deck/"+imported.ID+"/pile/hand", "alice")
+		hand := api.PileSerializer{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&hand))
+		assert.Equal(t, []string{"AS", "KH"}, model.CardCodes(hand.Cards), format)
+
+		resp = asPlayer(t, "GET", testSuite.ts.URL+"/deck/"+imported.ID+"/export", "bob")
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode, format)
+	}
+
+	// Without piles a snapshot still needs cards
+	resp = tenantRequest(t, "POST", testSuite.ts.URL+"/deck/import", "alice-key", `{"cards": []}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = tenantRequest(t, "POST", testSuite.ts.URL+"/deck/import", "alice-key", `{"cards": ["`+strings.Repeat("A", 1<<20)+`"]}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
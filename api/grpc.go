@@ -0,0 +1,214 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"toggl-test-wiliam/deckpb"
+	"toggl-test-wiliam/model"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// rpcCodes maps the statuses of deckError to gRPC codes
+var rpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:      codes.InvalidArgument,
+	http.StatusUnauthorized:    codes.Unauthenticated,
+	http.StatusForbidden:       codes.PermissionDenied,
+	http.StatusNotFound:        codes.NotFound,
+	http.StatusConflict:        codes.FailedPrecondition,
+	http.StatusGone:            codes.NotFound,
+	http.StatusTooManyRequests: codes.ResourceExhausted,
+}
+
+// GRPCServer serves the deck operations of deckpb.DeckService, sharing the
+// logic, authentication, deck tokens and rate limits of the HTTP handlers
+func (s *Server) GRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(append(opts, grpc.UnaryInterceptor(s.authenticateRPC))...)
+	deckpb.RegisterDeckServiceServer(server, &deckService{s: s})
+	return server
+}
+
+// authenticateRPC is authenticate for gRPC calls, reading the credentials
+// from the "x-api-key" or "authorization" metadata
+func (s *Server) authenticateRPC(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !s.authEnabled() {
+		return handler(ctx, req)
+	}
+
+	caller, err := s.principalOf(firstMetadata(ctx, "x-api-key"), firstMetadata(ctx, "authorization"))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Missing or invalid credentials")
+	}
+	return handler(context.WithValue(ctx, principalKey{}, caller), req)
+}
+
+type deckService struct {
+	deckpb.UnimplementedDeckServiceServer
+	s *Server
+}
+
+func (d *deckService) CreateDeck(ctx context.Context, req *deckpb.CreateDeckRequest) (*deckpb.CreateDeckResponse, error) {
+	c := rpcCaller(ctx)
+	if err := d.s.throttleRPC(ctx, d.s.createLimiter); err != nil {
+		return nil, err
+	}
+	if err := d.s.deckQuota(c.Tenant); err != nil {
+		return nil, rpcError(ctx, err)
+	}
+
+	deck, token, err := d.s.createDeck(c, CreateDeckRequest{
+		Cards:      req.Cards,
+		CardType:   req.CardType,
+		Shuffle:    req.Shuffle,
+		Owner:      req.Owner,
+		Visibility: req.Visibility,
+		Protected:  req.Protected,
+		TTL:        req.Ttl,
+	})
+	if err != nil {
+		return nil, rpcError(ctx, err)
+	}
+
+	return &deckpb.CreateDeckResponse{
+		DeckId:     deck.ID,
+		Shuffled:   deck.Shuffled,
+		Remaining:  int32(len(deck.Cards)),
+		Protected:  deck.Protected,
+		OwnerToken: token,
+	}, nil
+}
+
+func (d *deckService) OpenDeck(ctx context.Context, req *deckpb.OpenDeckRequest) (*deckpb.Deck, error) {
+	c := rpcCaller(ctx)
+	deck, err := d.s.deckFor(c, req.DeckId, model.RoleReadOnly)
+	if err != nil {
+		return nil, rpcError(ctx, err)
+	}
+	return protoDeck(deck, c), nil
+}
+
+func (d *deckService) DrawCards(ctx context.Context, req *deckpb.DrawCardsRequest) (*deckpb.DrawCardsResponse, error) {
+	if err := d.s.throttleRPC(ctx, d.s.drawLimiter); err != nil {
+		return nil, err
+	}
+
+	deck, err := d.s.deckFor(rpcCaller(ctx), req.DeckId, model.RoleDraw)
+	if err != nil {
+		return nil, rpcError(ctx, err)
+	}
+
+	count := int(req.Count)
+	if count == 0 {
+		count = 1
+	}
+	cards, err := d.s.drawCards(&deck, count)
+	if err != nil {
+		return nil, rpcError(ctx, err)
+	}
+
+	return &deckpb.DrawCardsResponse{Cards: protoCards(cards), Remaining: int32(deck.Remaining)}, nil
+}
+
+func (d *deckService) ShuffleDeck(ctx context.Context, req *deckpb.ShuffleDeckRequest) (*deckpb.Deck, error) {
+	if err := d.s.throttleRPC(ctx, d.s.drawLimiter); err != nil {
+		return nil, err
+	}
+
+	c := rpcCaller(ctx)
+	deck, err := d.s.deckFor(c, req.DeckId, model.RoleDealer)
+	if err != nil {
+		return nil, rpcError(ctx, err)
+	}
+
+	if err := d.s.shuffleDeck(&deck); err != nil {
+		return nil, rpcError(ctx, err)
+	}
+	return protoDeck(deck, c), nil
+}
+
+// rpcCaller is callerOf for gRPC calls, with the deck token in the
+// "x-deck-token" metadata
+func rpcCaller(ctx context.Context) caller {
+	return caller{Principal: principalIn(ctx), token: firstMetadata(ctx, "x-deck-token")}
+}
+
+// throttleRPC is throttled for gRPC calls, telling how long to wait in the
+// "retry-after" header
+func (s *Server) throttleRPC(ctx context.Context, limiter *rateLimiter) error {
+	remoteAddr := ""
+	if client, ok := peer.FromContext(ctx); ok {
+		remoteAddr = client.Addr.String()
+	}
+
+	ok, retryAfter := limiter.take(clientKey(principalIn(ctx), remoteAddr), s.Now())
+	if ok {
+		return nil
+	}
+	return rpcError(ctx, &deckError{status: http.StatusTooManyRequests, message: "Rate limit exceeded", retryAfter: retryAfter})
+}
+
+// rpcError turns a deckError into the status of the matching code, and any
+// other error into an internal one
+func rpcError(ctx context.Context, err error) error {
+	var failure *deckError
+	if !errors.As(err, &failure) {
+		failure = errDatabase
+	}
+
+	code, ok := rpcCodes[failure.status]
+	if !ok {
+		code = codes.Internal
+	}
+	if failure.retryAfter > 0 {
+		seconds := int(math.Max(1, math.Ceil(failure.retryAfter.Seconds())))
+		grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(seconds)))
+	}
+	return status.Error(code, failure.message)
+}
+
+func firstMetadata(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// protoDeck shows the remaining cards of the deck, face down to callers it
+// is hidden from
+func protoDeck(deck model.Deck, c caller) *deckpb.Deck {
+	cards := deck.Cards
+	if !deck.VisibleTo(c.id()) {
+		cards = redact(cards)
+	}
+
+	response := &deckpb.Deck{
+		DeckId:    deck.ID,
+		Shuffled:  deck.Shuffled,
+		Remaining: int32(len(deck.Cards)),
+		CardType:  deck.CardType,
+		CreatedBy: deck.CreatedBy,
+		Protected: deck.Protected,
+		SessionId: deck.SessionID,
+		Cards:     protoCards(cards),
+	}
+	if deck.ExpiresAt != nil {
+		response.ExpiresAt = timestamppb.New(*deck.ExpiresAt)
+	}
+	return response
+}
+
+func protoCards(cards []model.Card) []*deckpb.Card {
+	converted := make([]*deckpb.Card, 0, len(cards))
+	for _, card := range cards {
+		converted = append(converted, &deckpb.Card{Value: card.Value, Suit: card.Suit, Code: card.Code, Hidden: card.Hidden})
+	}
+	return converted
+}
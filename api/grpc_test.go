@@ -0,0 +1,145 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	api "toggl-test-wiliam/api"
+	"toggl-test-wiliam/deckpb"
+	model "toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dialGRPC serves the suite's server over gRPC in memory and connects to it
+func dialGRPC(t *testing.T, testSuite *APITestSuite) (deckpb.DeckServiceClient, func()) {
+	listener := bufconn.Listen(1 << 20)
+	server := testSuite.server.GRPCServer()
+	go server.Serve(listener)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	return deckpb.NewDeckServiceClient(conn), func() {
+		conn.Close()
+		server.Stop()
+	}
+}
+
+func TestGRPC_DeckOperations(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+	client, stop := dialGRPC(t, testSuite)
+	ctx := context.Background()
+
+	created, err := client.CreateDeck(ctx, &deckpb.CreateDeckRequest{Cards: []string{"AS", "KD", "QH", "JC"}})
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), created.Remaining)
+	assert.False(t, created.Shuffled)
+	assert.NotEmpty(t, created.OwnerToken)
+
+	deck, err := client.OpenDeck(ctx, &deckpb.OpenDeckRequest{DeckId: created.DeckId})
+	require.NoError(t, err)
+	assert.Equal(t, "FRENCH", deck.CardType)
+	assert.NotNil(t, deck.ExpiresAt)
+	require.Len(t, deck.Cards, 4)
+	assert.Equal(t, "JC", deck.Cards[3].Code)
+	assert.Equal(t, "JACK", deck.Cards[3].Value)
+
+	drawn, err := client.DrawCards(ctx, &deckpb.DrawCardsRequest{DeckId: created.DeckId, Count: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), drawn.Remaining)
+	assert.Equal(t, "QH", drawn.Cards[0].Code)
+	assert.Equal(t, "JC", drawn.Cards[1].Code)
+
+	shuffled, err := client.ShuffleDeck(ctx, &deckpb.ShuffleDeckRequest{DeckId: created.DeckId})
+	require.NoError(t, err)
+	assert.True(t, shuffled.Shuffled)
+	assert.Len(t, shuffled.Cards, 2)
+
+	_, err = client.DrawCards(ctx, &deckpb.DrawCardsRequest{DeckId: created.DeckId, Count: 3})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.OpenDeck(ctx, &deckpb.OpenDeckRequest{DeckId: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.CreateDeck(ctx, &deckpb.CreateDeckRequest{Cards: []string{"XX"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Both APIs share the deck and its history
+	resp, err := http.Get(testSuite.ts.URL + "/deck/" + created.DeckId + "/history")
+	require.NoError(t, err)
+	history := api.HistorySerializer{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&history))
+	types := []string{}
+	for _, event := range history.Events {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []string{model.EventCreated, model.EventDrawn, model.EventShuffled}, types)
+
+	stop()
+	testSuite.TearDownTest()
+}
+
+func TestGRPC_Credentials(t *testing.T) {
+	testSuite := setupTenants()
+	client, stop := dialGRPC(t, testSuite)
+	alice := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "alice-key")
+	bob := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "bob-key")
+
+	_, err := client.CreateDeck(context.Background(), &deckpb.CreateDeckRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	created, err := client.CreateDeck(alice, &deckpb.CreateDeckRequest{Protected: true, Visibility: "owner"})
+	require.NoError(t, err)
+
+	deck, err := client.OpenDeck(alice, &deckpb.OpenDeckRequest{DeckId: created.DeckId})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", deck.CreatedBy)
+	assert.Len(t, deck.Cards, 52)
+
+	// Decks of other tenants are not found
+	_, err = client.OpenDeck(bob, &deckpb.OpenDeckRequest{DeckId: created.DeckId})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// Deck tokens go in the metadata
+	resp := tenantRequest(t, "POST", testSuite.ts.URL+"/deck/"+created.DeckId+"/token?role=read_only", "alice-key", "")
+	token := api.DeckTokenSerializer{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&token))
+
+	testSuite.server.APIKeys["carol-key"] = api.Principal{Name: "carol", Tenant: "studio-a"}
+	carol := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "carol-key")
+	_, err = client.OpenDeck(carol, &deckpb.OpenDeckRequest{DeckId: created.DeckId})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	spectator := metadata.AppendToOutgoingContext(carol, "x-deck-token", token.Token)
+	deck, err = client.OpenDeck(spectator, &deckpb.OpenDeckRequest{DeckId: created.DeckId})
+	require.NoError(t, err)
+	assert.True(t, deck.Cards[0].Hidden)
+	_, err = client.DrawCards(spectator, &deckpb.DrawCardsRequest{DeckId: created.DeckId})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Rate limits apply to both APIs
+	testSuite.server.DrawLimit = api.RateLimit{Rate: 0.001, Burst: 1}
+	_, err = client.DrawCards(alice, &deckpb.DrawCardsRequest{DeckId: created.DeckId})
+	require.NoError(t, err)
+	var header metadata.MD
+	_, err = client.DrawCards(alice, &deckpb.DrawCardsRequest{DeckId: created.DeckId}, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.NotEmpty(t, header.Get("retry-after"))
+
+	stop()
+	testSuite.TearDownTest()
+}
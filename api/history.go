@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"toggl-test-wiliam/model"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type HistorySerializer struct {
+	ID     string            `json:"deck_id"`
+	Events []model.DeckEvent `json:"events"`
+}
+
+// DeckHistory lists every operation recorded against a deck, oldest first,
+// leaving out the cards of a hidden deck and of draws onto hidden piles. The
+// log of expired and purged decks is still served
+func (s *Server) DeckHistory(w http.ResponseWriter, r *http.Request) {
+	deck, err := s.deckOnRecord(tenantOf(r), mux.Vars(r)["deck_id"])
+	if err == nil {
+		err = s.authorize(callerOf(r), deck, model.RoleReadOnly)
+	}
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	events, err := model.Events(s.DB, deck.ID)
+	if err != nil {
+		s.Logger.Printf("load history of deck %s: %v", deck.ID, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	caller := callerID(r)
+	hiddenPiles, err := s.hiddenPiles(deck.ID, caller)
+	if err != nil {
+		s.Logger.Printf("list piles of deck %s: %v", deck.ID, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	for i := range events {
+		events[i] = redactEvent(events[i], deck.VisibleTo(caller), hiddenPiles)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := HistorySerializer{
+		ID:     deck.ID,
+		Events: events,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// redactEvent leaves out the cards the caller may not see: all of them when
+// the deck is hidden, and those of hidden piles otherwise
+func redactEvent(event model.DeckEvent, deckVisible bool, hiddenPiles map[string]bool) model.DeckEvent {
+	if !deckVisible || hiddenPiles[event.Pile] {
+		event.Cards = nil
+	}
+	if event.Piles != nil {
+		piles := map[string][]string{}
+		for name, codes := range event.Piles {
+			if deckVisible && !hiddenPiles[name] {
+				piles[name] = codes
+			}
+		}
+		event.Piles = piles
+	}
+	return event
+}
+
+// deckOnRecord is deckIn for the deck log, which outlives the deck: expired
+// and purged decks are found too
+func (s *Server) deckOnRecord(tenant, deckID string) (model.Deck, error) {
+	deck := model.Deck{}
+	if err := notInGame(inTenant(s.DB.Unscoped(), tenant)).Where("id = ?", deckID).Limit(1).Find(&deck).Error; err != nil {
+		s.Logger.Printf("load deck %s: %v", deckID, err)
+		return deck, errDatabase
+	}
+	if deck.ID == "" {
+		return deck, &deckError{status: http.StatusNotFound, message: "Deck not found"}
+	}
+	return deck, nil
+}
+
+// hiddenPiles tells, by name, which piles of the deck the caller may not see
+func (s *Server) hiddenPiles(deckID, caller string) (map[string]bool, error) {
+	var piles []model.Pile
+	if err := s.DB.Where("deck_id = ?", deckID).Find(&piles).Error; err != nil {
+		return nil, err
+	}
+
+	hidden := map[string]bool{}
+	for _, pile := range piles {
+		hidden[pile.Name] = !pile.VisibleTo(caller)
+	}
+	return hidden, nil
+}
+
+// replayDeck rebuilds the deck from its first upTo events, answering 404 when
+// the log is shorter than that
+func (s *Server) replayDeck(w http.ResponseWriter, deckID string, upTo int) (model.Deck, bool) {
+	var events []model.DeckEvent
+	err := s.DB.Where("deck_id = ? AND seq <= ?", deckID, upTo).Order("seq").Find(&events).Error
+	if err != nil {
+		s.Logger.Printf("load history of deck %s: %v", deckID, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return model.Deck{}, false
+	}
+
+	if len(events) < upTo {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return model.Deck{}, false
+	}
+
+	deck, err := model.Replay(events)
+	if err != nil {
+		s.Logger.Printf("replay deck %s: %v", deckID, err)
+		http.Error(w, "corrupt deck history", http.StatusInternalServerError)
+		return model.Deck{}, false
+	}
+	return deck, true
+}
+
+// recordEvents appends the events to the deck's log inside the caller's
+// transaction, which publishes them to the deck's subscribers once committed
+func (s *Server) recordEvents(tx *gorm.DB, deckID string, events ...model.DeckEvent) error {
+	for i := range events {
+		events[i].DeckID = deckID
+		events[i].CreatedAt = s.Now().UTC()
+		if err := model.AppendEvent(tx, &events[i]); err != nil {
+			return err
+		}
+	}
+
+	if pending, ok := tx.Statement.Context.Value(pendingEventsKey{}).(*pendingEvents); ok {
+		pending.events = append(pending.events, events...)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	api "toggl-test-wiliam/api"
+	model "toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeckHistory_RecordsOperations(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck?shuffle=true&cards=AS,KS,QS,JS", "application/json", nil)
+	assert.NoError(t, err)
+
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "/draw?count=2")
+	assert.NoError(t, err)
+
+	drawn := []model.Card{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&drawn))
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "/history")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	history := api.HistorySerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&history))
+	assert.Equal(t, deck.ID, history.ID)
+	assert.Len(t, history.Events, 3)
+
+	assert.Equal(t, 1, history.Events[0].Seq)
+	assert.Equal(t, model.EventCreated, history.Events[0].Type)
+	assert.Equal(t, []string{"AS", "KS", "QS", "JS"}, history.Events[0].Cards)
+
+	assert.Equal(t, 2, history.Events[1].Seq)
+	assert.Equal(t, model.EventShuffled, history.Events[1].Type)
+
+	assert.Equal(t, 3, history.Events[2].Seq)
+	assert.Equal(t, model.EventDrawn, history.Events[2].Type)
+	assert.Equal(t, 2, history.Events[2].Count)
+	assert.Equal(t, model.CardCodes(drawn), history.Events[2].Cards)
+
+	testSuite.TearDownTest()
+}
+
+func TestOpenDeck_AtEvent(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck?shuffle=true&cards=AS,KS,QS,JS", "application/json", nil)
+	assert.NoError(t, err)
+
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID)
+	assert.NoError(t, err)
+
+	shuffled := api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&shuffled))
+
+	_, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "/draw?count=3")
+	assert.NoError(t, err)
+
+	// Right after creation the deck still holds the requested order
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "?at=1")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	historical := api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&historical))
+	assert.Equal(t, 1, historical.EventNo)
+	assert.False(t, historical.Shuffled)
+	assert.Equal(t, []string{"AS", "KS", "QS", "JS"}, model.CardCodes(historical.Cards))
+
+	// Replaying the shuffle reproduces the order the deck was dealt from
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "?at=2")
+	assert.NoError(t, err)
+
+	historical = api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&historical))
+	assert.True(t, historical.Shuffled)
+	assert.Equal(t, shuffled.Cards, historical.Cards)
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "?at=3")
+	assert.NoError(t, err)
+
+	historical = api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&historical))
+	assert.Equal(t, 1, historical.Remaining)
+	assert.Equal(t, shuffled.Cards[:1], historical.Cards)
+
+	testSuite.TearDownTest()
+}
+
+func TestOpenDeck_AtUnknownEvent(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck", "application/json", nil)
+	assert.NoError(t, err)
+
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	resp, _ = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "?at=2")
+	resp_body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, "Event not found\n", string(resp_body))
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, _ = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "?at=zero")
+	resp_body, _ = ioutil.ReadAll(resp.Body)
+	assert.Equal(t, "invalid at: zero\n", string(resp_body))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
+
+func TestDeckHistory_NotFound(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, _ := http.Get(testSuite.ts.URL + "/deck/zxczxczxc/history")
+	resp_body, _ := ioutil.ReadAll(resp.Body)
+
+	assert.Equal(t, "Deck not found\n", string(resp_body))
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
+
+func TestDeckHistory_OutlivesDeck(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck?cards=AS,KS", "application/json", nil)
+	assert.NoError(t, err)
+
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	_, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "/draw?count=2")
+	assert.NoError(t, err)
+
+	purged, err := testSuite.server.PurgeDecks()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "/history")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	history := api.HistorySerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&history))
+	assert.Len(t, history.Events, 2)
+	assert.Equal(t, []string{"AS", "KS"}, history.Events[1].Cards)
+
+	testSuite.TearDownTest()
+}
+
+func TestOpenDeck_AtEventRebuildsPiles(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck?cards=AS,KS,QS,JS", "application/json", nil)
+	assert.NoError(t, err)
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	_, err = http.Post(testSuite.ts.URL+"/deck/"+deck.ID+"/pile?name=discard", "application/json", nil)
+	assert.NoError(t, err)
+	_, err = http.Post(testSuite.ts.URL+"/deck/"+deck.ID+"/pile/discard/draw?count=2", "application/json", nil)
+	assert.NoError(t, err)
+	_, err = http.Post(testSuite.ts.URL+"/deck/"+deck.ID+"/pile/discard/draw", "application/json", nil)
+	assert.NoError(t, err)
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "?at=2")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	historical := api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&historical))
+	assert.Equal(t, 2, historical.Remaining)
+	assert.Len(t, historical.Piles, 1)
+	assert.Equal(t, "discard", historical.Piles[0].Name)
+	assert.Equal(t, []string{"QS", "JS"}, model.CardCodes(historical.Piles[0].Cards))
+
+	testSuite.TearDownTest()
+}
@@ -0,0 +1,267 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"toggl-test-wiliam/game/holdem"
+	"toggl-test-wiliam/model"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type TableSeatSerializer struct {
+	Seat   int      `json:"seat"`
+	Player string   `json:"player,omitempty"`
+	Cards  []string `json:"cards,omitempty"`
+	Hand   string   `json:"hand,omitempty"`
+	Ranks  []int    `json:"ranks,omitempty"`
+	Best   []string `json:"best,omitempty"`
+}
+
+type TableSerializer struct {
+	ID      string                `json:"table_id"`
+	HandNo  int                   `json:"hand_no"`
+	Street  string                `json:"street"`
+	Button  int                   `json:"button"`
+	Burned  int                   `json:"burned"`
+	Board   []string              `json:"board"`
+	Seats   []TableSeatSerializer `json:"seats"`
+	Winners []int                 `json:"winners,omitempty"`
+}
+
+// CreateTable opens a table of "seats" empty seats; the caller sits down at
+// the "seat" query parameter when given. Hands are dealt once players sat down
+func (s *Server) CreateTable(w http.ResponseWriter, r *http.Request) {
+	seats := 6
+	if seatsParam := r.URL.Query().Get("seats"); seatsParam != "" {
+		var err error
+		if seats, err = strconv.Atoi(seatsParam); err != nil {
+			http.Error(w, "invalid seats: "+seatsParam, http.StatusBadRequest)
+			return
+		}
+	}
+
+	table, err := holdem.NewTable(seats)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	table.Tenant = tenantOf(r)
+	if r.URL.Query().Get("seat") != "" && !sitAt(w, r, table) {
+		return
+	}
+
+	if err := s.DB.Create(table).Error; err != nil {
+		s.Logger.Printf("create table: %v", err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tableResponse(table, tableViewer(r, table)))
+}
+
+// OpenTable shows the table; hole cards stay hidden until the showdown
+// except for the caller's own seat
+func (s *Server) OpenTable(w http.ResponseWriter, r *http.Request) {
+	table, ok := s.findTable(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tableResponse(table, tableViewer(r, table)))
+}
+
+// SitAtTable binds the seat given by the "seat" query parameter to the caller
+func (s *Server) SitAtTable(w http.ResponseWriter, r *http.Request) {
+	table, ok := s.findTable(w, r)
+	if !ok {
+		return
+	}
+	if !sitAt(w, r, table) {
+		return
+	}
+
+	if err := s.DB.Save(table).Error; err != nil {
+		s.Logger.Printf("save table %s: %v", table.ID, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tableResponse(table, tableViewer(r, table)))
+}
+
+// DealTableHand starts the next hand on a fresh deck once the last one
+// reached its showdown, for a player seated at the table
+func (s *Server) DealTableHand(w http.ResponseWriter, r *http.Request) {
+	table, ok := s.findTable(w, r)
+	if !ok || !seatedAt(w, r, table) {
+		return
+	}
+
+	if !s.dealTableHand(w, r, table) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tableResponse(table, tableViewer(r, table)))
+}
+
+// NextStreet deals the flop, turn or river from the hand's deck, or settles
+// the showdown, for a player seated at the table
+func (s *Server) NextStreet(w http.ResponseWriter, r *http.Request) {
+	table, ok := s.findTable(w, r)
+	if !ok || !seatedAt(w, r, table) {
+		return
+	}
+	deck, err := s.gameDeck(tenantOf(r), table.ID, table.DeckID)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+
+	draw, drawn := drawFrom(&deck, holdem.ErrHandOver)
+	if err := table.Next(draw); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	err = s.transaction(s.DB, func(tx *gorm.DB) error {
+		if err := tx.Save(&deck).Error; err != nil {
+			return err
+		}
+		if err := s.recordEvents(tx, deck.ID, *drawn...); err != nil {
+			return err
+		}
+		return tx.Save(table).Error
+	})
+	if err != nil {
+		s.Logger.Printf("save table %s: %v", table.ID, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tableResponse(table, tableViewer(r, table)))
+}
+
+// dealTableHand shuffles a new French deck for the table's next hand and
+// deals the hole cards from it, saving the deck, its history and the table.
+// The deck is a face down deck of the table, out of reach of the deck endpoints
+func (s *Server) dealTableHand(w http.ResponseWriter, r *http.Request, table *holdem.Table) bool {
+	deck := model.Deck{}
+	deck, err := deck.Create(s.frenchCodes())
+	if err != nil {
+		s.Logger.Printf("create deck for table %s: %v", table.ID, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return false
+	}
+	deck.ExpiresAt, _ = s.expiresAt("")
+	stampCreator(r, &deck)
+	deck.GameID = table.ID
+	deck.Visibility = model.VisibilityFaceDown
+
+	events := []model.DeckEvent{{Type: model.EventCreated, Cards: model.CardCodes(deck.Cards)}}
+	seed := s.shuffleSeed()
+	deck.ShuffleSeed(seed)
+	events = append(events, model.DeckEvent{Type: model.EventShuffled, Seed: seed})
+
+	draw, drawn := drawFrom(&deck, holdem.ErrHandOver)
+	if err := table.Deal(deck.ID, draw); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return false
+	}
+
+	err = s.transaction(s.DB, func(tx *gorm.DB) error {
+		if err := tx.Create(&deck).Error; err != nil {
+			return err
+		}
+		if err := s.recordEvents(tx, deck.ID, append(events, *drawn...)...); err != nil {
+			return err
+		}
+		return tx.Save(table).Error
+	})
+	if err != nil {
+		s.Logger.Printf("deal hand at table %s: %v", table.ID, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+func (s *Server) findTable(w http.ResponseWriter, r *http.Request) (*holdem.Table, bool) {
+	table := &holdem.Table{}
+	if err := inTenant(s.DB, tenantOf(r)).First(table, "id = ?", mux.Vars(r)["table_id"]).Error; err != nil {
+		http.Error(w, "Table not found", http.StatusNotFound)
+		return nil, false
+	}
+	return table, true
+}
+
+// sitAt seats the authenticated caller at the "seat" query parameter. Seats
+// show their hole cards to whoever holds them, so a name given as the
+// "player" query parameter, which anyone could claim, does not take one
+func sitAt(w http.ResponseWriter, r *http.Request, table *holdem.Table) bool {
+	seatParam := r.URL.Query().Get("seat")
+	seat, err := strconv.Atoi(seatParam)
+	if err != nil {
+		http.Error(w, "invalid seat: "+seatParam, http.StatusBadRequest)
+		return false
+	}
+	player := principal(r)
+	if player == "" {
+		http.Error(w, "Taking a seat needs an authenticated caller", http.StatusForbidden)
+		return false
+	}
+
+	switch err := table.Sit(seat, player); {
+	case errors.Is(err, holdem.ErrNoSuchSeat):
+		http.Error(w, "invalid seat: "+seatParam, http.StatusBadRequest)
+		return false
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+// seatedAt answers 403 unless the caller sits at the table
+func seatedAt(w http.ResponseWriter, r *http.Request, table *holdem.Table) bool {
+	if tableViewer(r, table) < 0 {
+		http.Error(w, "Only players seated at the table may deal", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// tableViewer is the seat of the authenticated caller, -1 when they sit at no seat
+func tableViewer(r *http.Request, table *holdem.Table) int {
+	return table.SeatOf(principal(r))
+}
+
+func tableResponse(table *holdem.Table, viewer int) TableSerializer {
+	response := TableSerializer{
+		ID:      table.ID,
+		HandNo:  table.HandNo,
+		Street:  table.Street,
+		Button:  table.Button,
+		Burned:  len(table.Burned),
+		Board:   table.Board,
+		Seats:   []TableSeatSerializer{},
+		Winners: table.Winners,
+	}
+
+	for i, seat := range table.Seats {
+		serialized := TableSeatSerializer{Seat: i, Player: seat.Player, Hand: seat.Hand, Ranks: seat.Ranks, Best: seat.Best}
+		if table.Street == holdem.StreetShowdown || i == viewer {
+			serialized.Cards = seat.Cards
+		}
+		response.Seats = append(response.Seats, serialized)
+	}
+	return response
+}
@@ -0,0 +1,193 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"testing"
+	api "toggl-test-wiliam/api"
+	"toggl-test-wiliam/game/holdem"
+	model "toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTable_PlayHand(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	testSuite.server.APIKeys = playerKeys
+
+	resp := asPlayer(t, "POST", testSuite.ts.URL+"/table?seats=3&seat=1", "alice")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	table := api.TableSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&table))
+	assert.NotEmpty(t, table.ID)
+	assert.Equal(t, 0, table.HandNo)
+	assert.Len(t, table.Seats, 3)
+	assert.Equal(t, "alice", table.Seats[1].Player)
+	url := testSuite.ts.URL + "/table/" + table.ID
+
+	// A hand takes two players, and only they may deal it
+	resp = asPlayer(t, "POST", url+"/deal", "alice")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	asPlayer(t, "POST", url+"/sit?seat=0", "bob")
+	resp = asPlayer(t, "POST", url+"/deal", "carol")
+	resp_body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "Only players seated at the table may deal\n", string(resp_body))
+
+	resp = asPlayer(t, "POST", url+"/deal", "alice")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	table = api.TableSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&table))
+	assert.Equal(t, 1, table.HandNo)
+	assert.Equal(t, 0, table.Button)
+	assert.Equal(t, holdem.StreetPreflop, table.Street)
+
+	// Only the viewer's hole cards are shown before the showdown
+	assert.Empty(t, table.Seats[0].Cards)
+	assert.Len(t, table.Seats[1].Cards, 2)
+	assert.Empty(t, table.Seats[2].Cards)
+
+	resp = asPlayer(t, "GET", url, "carol")
+	spectated := api.TableSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&spectated))
+	for _, seat := range spectated.Seats {
+		assert.Empty(t, seat.Cards)
+	}
+
+	resp = asPlayer(t, "POST", url+"/next", "carol")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	for _, street := range []string{holdem.StreetFlop, holdem.StreetTurn, holdem.StreetRiver, holdem.StreetShowdown} {
+		resp = asPlayer(t, "POST", url+"/next", "bob")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		table = api.TableSerializer{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&table))
+		assert.Equal(t, street, table.Street)
+	}
+	assert.Len(t, table.Board, 5)
+	assert.Equal(t, 3, table.Burned)
+	assert.NotEmpty(t, table.Winners)
+	assert.NotContains(t, table.Winners, 2)
+	for _, seat := range table.Seats[:2] {
+		assert.Len(t, seat.Cards, 2)
+		assert.NotEmpty(t, seat.Hand)
+	}
+	// The empty seat sits the hand out
+	assert.Empty(t, table.Seats[2].Cards)
+	assert.Empty(t, table.Seats[2].Hand)
+
+	resp = asPlayer(t, "POST", url+"/next", "bob")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	// Every hand is dealt from a deck of its own
+	resp = asPlayer(t, "POST", url+"/deal", "bob")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	table = api.TableSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&table))
+	assert.Equal(t, 2, table.HandNo)
+	assert.Equal(t, 1, table.Button)
+	assert.Empty(t, table.Board)
+
+	var count int64
+	testSuite.db.Model(model.Deck{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+
+	resp = asPlayer(t, "POST", url+"/deal", "bob")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
+
+func TestTable_HidesDeckOrder(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/table?seats=2", "application/json", nil)
+	assert.NoError(t, err)
+
+	var raw map[string]interface{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&raw))
+	assert.NotContains(t, raw, "deck_id")
+	assert.NotContains(t, raw, "cards")
+
+	resp, _ = http.Post(testSuite.ts.URL+"/table?seats=11", "application/json", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = http.Get(testSuite.ts.URL + "/table/unknown")
+	resp_body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "Table not found\n", string(resp_body))
+
+	testSuite.TearDownTest()
+}
+
+func TestTable_SeatsBoundToCaller(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	// Without authentication nobody can take a seat, whatever player they claim
+	resp, _ := http.Post(testSuite.ts.URL+"/table?seats=2&seat=0&player=alice", "application/json", nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	testSuite.server.APIKeys = playerKeys
+	resp = asPlayer(t, "POST", testSuite.ts.URL+"/table?seats=2&seat=0", "alice")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	table := api.TableSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&table))
+
+	resp = asPlayer(t, "POST", testSuite.ts.URL+"/table/"+table.ID+"/sit?seat=0", "bob")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp = asPlayer(t, "POST", testSuite.ts.URL+"/table/"+table.ID+"/sit?seat=2", "bob")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp = asPlayer(t, "POST", testSuite.ts.URL+"/table/"+table.ID+"/sit?seat=1", "bob")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = asPlayer(t, "POST", testSuite.ts.URL+"/table/"+table.ID+"/deal", "bob")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Each player only sees their own hole cards, asking for another seat shows nothing more
+	for player, seat := range map[string]int{"alice": 0, "bob": 1} {
+		resp = asPlayer(t, "GET", testSuite.ts.URL+"/table/"+table.ID+"?seat="+strconv.Itoa(1-seat), player)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		viewed := api.TableSerializer{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&viewed))
+		assert.Len(t, viewed.Seats[seat].Cards, 2)
+		assert.Empty(t, viewed.Seats[1-seat].Cards)
+	}
+
+	testSuite.TearDownTest()
+}
+
+func TestTable_DeckIsHidden(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	testSuite.server.APIKeys = playerKeys
+	resp := asPlayer(t, "POST", testSuite.ts.URL+"/table?seats=2&seat=0", "alice")
+	table := api.TableSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&table))
+	asPlayer(t, "POST", testSuite.ts.URL+"/table/"+table.ID+"/sit?seat=1", "bob")
+	asPlayer(t, "POST", testSuite.ts.URL+"/table/"+table.ID+"/deal", "bob")
+	testSuite.server.APIKeys = nil
+
+	deck := model.Deck{}
+	assert.NoError(t, testSuite.db.First(&deck).Error)
+	assert.Equal(t, table.ID, deck.GameID)
+	assert.Equal(t, model.VisibilityFaceDown, deck.Visibility)
+
+	for _, path := range []string{"", "/history", "/draw", "/export"} {
+		resp, _ = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + path)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, path)
+	}
+
+	resp, _ = http.Get(testSuite.ts.URL + "/deck")
+	list := api.ListDecksSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	assert.Empty(t, list.Decks)
+
+	testSuite.TearDownTest()
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"toggl-test-wiliam/model"
+)
+
+// DefaultImageBaseURL is where the bundled card SVGs are served from
+const DefaultImageBaseURL = "/static/cards"
+
+var includeOptions = map[string]bool{
+	"image": true,
+	"glyph": true,
+}
+
+// cardOptions lists what to add to the cards of a response on top of their code, value and suit
+type cardOptions struct {
+	include map[string]bool
+	rules   *model.Ruleset
+}
+
+// parseCardOptions reads the comma separated "include" query parameter and the "rules" one
+func parseCardOptions(r *http.Request) (cardOptions, error) {
+	options := cardOptions{include: map[string]bool{}}
+
+	if includeParam := r.URL.Query().Get("include"); includeParam != "" {
+		for _, option := range strings.Split(includeParam, ",") {
+			if !includeOptions[option] {
+				return options, fmt.Errorf("invalid include: %s", option)
+			}
+			options.include[option] = true
+		}
+	}
+
+	if rulesParam := r.URL.Query().Get("rules"); rulesParam != "" {
+		rules, ok := model.LookupRules(rulesParam)
+		if !ok {
+			return options, fmt.Errorf("invalid rules: %s", rulesParam)
+		}
+		options.rules = &rules
+	}
+	return options, nil
+}
+
+// withExtras returns a copy of the cards carrying the requested image URLs,
+// glyphs and rule attributes; cards without bundled artwork get no image or glyph
+func (s *Server) withExtras(cards []model.Card, options cardOptions) []model.Card {
+	if len(options.include) == 0 && options.rules == nil {
+		return cards
+	}
+
+	decorated := make([]model.Card, len(cards))
+	for i, card := range cards {
+		glyph := model.CardGlyph(card.Code)
+		if glyph != "" {
+			if options.include["image"] {
+				card.Image = strings.TrimSuffix(s.ImageBaseURL, "/") + "/" + card.Code + ".svg"
+			}
+			if options.include["glyph"] {
+				card.Glyph = glyph
+			}
+		}
+		if options.rules != nil {
+			card = options.rules.Annotate(card)
+		}
+		decorated[i] = card
+	}
+	return decorated
+}
@@ -0,0 +1,120 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	api "toggl-test-wiliam/api"
+	model "toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenDeck_WithInclude(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+	testSuite.server.ImageBaseURL = "https://cdn.example.com/cards/"
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck?cards=AS,10H", "application/json", nil)
+	assert.NoError(t, err)
+
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "?include=image,glyph")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	opened := api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&opened))
+	assert.Equal(t, "https://cdn.example.com/cards/AS.svg", opened.Cards[0].Image)
+	assert.Equal(t, "🂡", opened.Cards[0].Glyph)
+	assert.Equal(t, "https://cdn.example.com/cards/10H.svg", opened.Cards[1].Image)
+	assert.Equal(t, "🂺", opened.Cards[1].Glyph)
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "/draw?include=glyph")
+	assert.NoError(t, err)
+
+	drawn := []model.Card{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&drawn))
+	assert.Equal(t, "🂺", drawn[0].Glyph)
+	assert.Empty(t, drawn[0].Image)
+
+	// The extras are only added to responses, never persisted with the deck
+	var stored model.Deck
+	testSuite.db.First(&stored, "id = ?", deck.ID)
+	assert.Empty(t, stored.Cards[0].Glyph)
+
+	testSuite.TearDownTest()
+}
+
+func TestOpenDeck_WithInvalidInclude(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck", "application/json", nil)
+	assert.NoError(t, err)
+
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	resp, _ = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "?include=sound")
+	resp_body, _ := ioutil.ReadAll(resp.Body)
+
+	assert.Equal(t, "invalid include: sound\n", string(resp_body))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
+
+func TestDrawCards_WithRules(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck?cards=2C,AS,KH", "application/json", nil)
+	assert.NoError(t, err)
+
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "/draw?count=2&rules=bridge")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	drawn := []model.Card{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&drawn))
+	assert.Equal(t, 14, drawn[0].Rank)
+	assert.Equal(t, "BLACK", drawn[0].Color)
+	assert.Equal(t, 4, *drawn[0].Points)
+	assert.Equal(t, 13, drawn[1].Rank)
+	assert.Equal(t, "RED", drawn[1].Color)
+	assert.Equal(t, 3, *drawn[1].Points)
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "?rules=ace_low")
+	assert.NoError(t, err)
+
+	opened := api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&opened))
+	assert.Equal(t, 2, opened.Cards[0].Rank)
+	assert.Nil(t, opened.Cards[0].Points)
+
+	resp, _ = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "?rules=canasta")
+	resp_body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, "invalid rules: canasta\n", string(resp_body))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
+
+func TestStaticCardImages(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Get(testSuite.ts.URL + api.DefaultImageBaseURL + "/QH.svg")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "image/svg+xml", resp.Header.Get("Content-Type"))
+
+	testSuite.TearDownTest()
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"time"
+	"toggl-test-wiliam/model"
+
+	"gorm.io/gorm"
+)
+
+// StartJanitor purges expired and fully drawn decks every interval until ctx is cancelled
+func (s *Server) StartJanitor(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.PurgeDecks(); err != nil {
+					s.Logger.Printf("purge decks: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// PurgeDecks drops the cards and webhooks of every expired or fully drawn
+// deck and returns how many decks were removed. The deck itself stays behind
+// soft-deleted, with its event log, piles and tokens, so that its history can
+// still settle disputes. Decks of a session, and decks whose cards were all
+// dealt into piles that still hold some, are kept until they expire, as they
+// are still played with. The webhooks of expired decks are told
+// once they are gone
+func (s *Server) PurgeDecks() (int64, error) {
+	var purged int64
+	var expired []model.Deck
+	hooks := map[string][]model.Webhook{}
+
+	now := s.Now().UTC()
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var ids []string
+		holdsCards := tx.Model(&model.Pile{}).Select("1").Where("piles.deck_id = decks.id AND length(piles.cards) > 2")
+		err := tx.Model(&model.Deck{}).
+			Where("expires_at <= ? OR (remaining = 0 AND COALESCE(session_id, '') = '' AND NOT EXISTS (?))", now, holdsCards).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		err = tx.Unscoped().Select("id", "tenant", "remaining").Where("id IN ? AND expires_at <= ?", ids, now).Find(&expired).Error
+		if err != nil {
+			return err
+		}
+		for _, deck := range expired {
+			if hooks[deck.ID], err = s.webhooksOf(tx, deck); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("deck_id IN ?", ids).Delete(&model.Webhook{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&model.Deck{}).Where("id IN ?", ids).UpdateColumns(map[string]interface{}{"cards": nil, "deleted_at": now})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, deck := range expired {
+		s.dispatchWebhooks(hooks[deck.ID], deck, model.EventExpired, nil)
+	}
+	return purged, nil
+}
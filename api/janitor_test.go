@@ -0,0 +1,89 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+	api "toggl-test-wiliam/api"
+	model "toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgeDecks(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	now := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	testSuite.server.Now = func() time.Time { return now }
+
+	expired := now.Add(-time.Second)
+	live := now.Add(time.Hour)
+	card := []model.Card{{Value: "ACE", Suit: "CLUBS", Code: "AC"}}
+
+	testSuite.db.Create(&model.Deck{ID: "expired", Remaining: 1, ExpiresAt: &expired, Cards: card})
+	testSuite.db.Create(&model.Deck{ID: "drawn", Remaining: 0, ExpiresAt: &live, Cards: []model.Card{}})
+	testSuite.db.Create(&model.Deck{ID: "live", Remaining: 1, ExpiresAt: &live, Cards: card})
+	testSuite.db.Create(&model.Deck{ID: "forever", Remaining: 1, Cards: card})
+
+	testSuite.db.Create(&model.DeckEvent{DeckID: "expired", Seq: 1, Type: model.EventCreated})
+	testSuite.db.Create(&model.DeckEvent{DeckID: "live", Seq: 1, Type: model.EventCreated})
+
+	purged, err := testSuite.server.PurgeDecks()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), purged)
+
+	var ids []string
+	testSuite.db.Model(&model.Deck{}).Order("id").Pluck("id", &ids)
+	assert.Equal(t, []string{"forever", "live"}, ids)
+
+	// Purged decks stay on record without their cards, their log intact
+	purgedDeck := model.Deck{}
+	testSuite.db.Unscoped().First(&purgedDeck, "id = ?", "expired")
+	assert.True(t, purgedDeck.DeletedAt.Valid)
+	assert.Empty(t, purgedDeck.Cards)
+
+	var eventDecks []string
+	testSuite.db.Model(&model.DeckEvent{}).Order("deck_id").Pluck("deck_id", &eventDecks)
+	assert.Equal(t, []string{"expired", "live"}, eventDecks)
+
+	purged, err = testSuite.server.PurgeDecks()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), purged)
+
+	testSuite.TearDownTest()
+}
+
+func TestPurgeDecks_KeepsDecksDealtIntoPiles(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck?cards=AS,KD", "application/json", nil)
+	assert.NoError(t, err)
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+	url := testSuite.ts.URL + "/deck/" + deck.ID
+
+	http.Post(url+"/pile?name=table", "application/json", nil)
+	resp, _ = http.Post(url+"/pile/table/draw?count=2", "application/json", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	purged, err := testSuite.server.PurgeDecks()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), purged)
+
+	resp, _ = http.Get(url + "/pile/table")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	pile := api.PileSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&pile))
+	assert.Equal(t, []string{"AS", "KD"}, model.CardCodes(pile.Cards))
+
+	// Once the piles are empty again the deck is done with
+	testSuite.db.Model(&model.Pile{}).Where("deck_id = ?", deck.ID).Update("cards", "[]")
+	purged, err = testSuite.server.PurgeDecks()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	testSuite.TearDownTest()
+}
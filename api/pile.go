@@ -0,0 +1,248 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"toggl-test-wiliam/model"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type PileSerializer struct {
+	DeckID     string       `json:"deck_id"`
+	Name       string       `json:"name"`
+	Owner      string       `json:"owner,omitempty"`
+	Visibility string       `json:"visibility"`
+	Count      int          `json:"count"`
+	Cards      []model.Card `json:"cards"`
+}
+
+type ListPilesSerializer struct {
+	Piles []PileSerializer `json:"piles"`
+}
+
+const deckHiddenMessage = "Deck is not visible to you"
+
+// CreatePile adds an empty pile named "name" to a deck, owned by "owner" (the
+// caller by default) and shown according to "visibility". Session decks keep
+// their hands to themselves, so nobody can claim one ahead of its player
+func (s *Server) CreatePile(w http.ResponseWriter, r *http.Request) {
+	deck, ok := s.findDeck(w, r, model.RoleDealer)
+	if !ok || !outsideSession(w, deck) {
+		return
+	}
+
+	query := r.URL.Query()
+	name := query.Get("name")
+	if name == "" {
+		http.Error(w, "missing name", http.StatusBadRequest)
+		return
+	}
+	visibility, err := model.ParseVisibility(query.Get("visibility"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := callerOf(r).canOwn(visibility); err != nil {
+		fail(w, err)
+		return
+	}
+
+	pile := model.Pile{
+		DeckID:     deck.ID,
+		Name:       name,
+		Owner:      query.Get("owner"),
+		Visibility: visibility,
+		Cards:      []string{},
+	}
+	if pile.Owner == "" {
+		pile.Owner = callerID(r)
+	}
+
+	if err := s.DB.Where("deck_id = ? AND name = ?", deck.ID, name).First(&model.Pile{}).Error; err == nil {
+		http.Error(w, "Pile already exists", http.StatusConflict)
+		return
+	}
+	if err := s.DB.Create(&pile).Error; err != nil {
+		s.Logger.Printf("create pile %s of deck %s: %v", name, deck.ID, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.pileResponse(deck, pile, callerID(r)))
+}
+
+// ListPiles shows every pile of a deck, redacting the cards the caller may not see
+func (s *Server) ListPiles(w http.ResponseWriter, r *http.Request) {
+	deck, ok := s.findDeck(w, r, model.RoleReadOnly)
+	if !ok {
+		return
+	}
+
+	var piles []model.Pile
+	if err := s.DB.Where("deck_id = ?", deck.ID).Order("id").Find(&piles).Error; err != nil {
+		s.Logger.Printf("list piles of deck %s: %v", deck.ID, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	response := ListPilesSerializer{Piles: []PileSerializer{}}
+	for _, pile := range piles {
+		response.Piles = append(response.Piles, s.pileResponse(deck, pile, callerID(r)))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// OpenPile shows a pile, redacting its cards when the caller may not see them
+func (s *Server) OpenPile(w http.ResponseWriter, r *http.Request) {
+	deck, ok := s.findDeck(w, r, model.RoleReadOnly)
+	if !ok {
+		return
+	}
+	pile, ok := s.findPile(w, deck.ID, mux.Vars(r)["name"])
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.pileResponse(deck, pile, callerID(r)))
+}
+
+// DrawToPile draws "count" cards off the deck onto the top of a pile
+func (s *Server) DrawToPile(w http.ResponseWriter, r *http.Request) {
+	deck, ok := s.findDeck(w, r, model.RoleDraw)
+	if !ok || !outsideSession(w, deck) {
+		return
+	}
+	pile, ok := s.findPile(w, deck.ID, mux.Vars(r)["name"])
+	if !ok {
+		return
+	}
+
+	count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+	if count == 0 {
+		count = 1
+	}
+	if count < 1 || count > len(deck.Cards) {
+		http.Error(w, "Not enough cards in the deck", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.drawToPile(s.DB, &deck, &pile, count); err != nil {
+		s.Logger.Printf("draw deck %s to pile %s: %v", deck.ID, pile.Name, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.pileResponse(deck, pile, callerID(r)))
+}
+
+// drawToPile moves count cards from the deck onto the pile, recording the
+// draw against the pile so that an undo can take them back
+func (s *Server) drawToPile(db *gorm.DB, deck *model.Deck, pile *model.Pile, count int) error {
+	cards, err := deck.Draw(count)
+	if err != nil {
+		return err
+	}
+	codes := model.CardCodes(cards)
+	pile.Cards = append(pile.Cards, codes...)
+
+	return s.transaction(db, func(tx *gorm.DB) error {
+		if err := tx.Save(deck).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(pile).Error; err != nil {
+			return err
+		}
+		return s.recordEvents(tx, deck.ID, model.DeckEvent{
+			Type:  model.EventDrawn,
+			Count: count,
+			Cards: codes,
+			Pile:  pile.Name,
+		})
+	})
+}
+
+// historicalPiles shows the piles of the deck holding the replayed cards,
+// redacting those the caller may not see
+func (s *Server) historicalPiles(w http.ResponseWriter, r *http.Request, deck model.Deck, replayed map[string][]string) ([]PileSerializer, bool) {
+	var piles []model.Pile
+	if err := s.DB.Where("deck_id = ?", deck.ID).Order("id").Find(&piles).Error; err != nil {
+		s.Logger.Printf("list piles of deck %s: %v", deck.ID, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	response := []PileSerializer{}
+	for _, pile := range piles {
+		pile.Cards = replayed[pile.Name]
+		response = append(response, s.pileResponse(deck, pile, callerID(r)))
+	}
+	return response, true
+}
+
+func (s *Server) findPile(w http.ResponseWriter, deckID, name string) (model.Pile, bool) {
+	pile := model.Pile{}
+	err := s.DB.Where("deck_id = ? AND name = ?", deckID, name).First(&pile).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "Pile not found", http.StatusNotFound)
+		return pile, false
+	} else if err != nil {
+		s.Logger.Printf("load pile %s of deck %s: %v", name, deckID, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return pile, false
+	}
+	return pile, true
+}
+
+// callerID is the identity cards are shown to: the authenticated caller, or
+// nobody while authentication is off
+func callerID(r *http.Request) string {
+	return callerOf(r).id()
+}
+
+// visibleOnly answers 403 for callers the deck order is hidden from, for
+// endpoints that would hand it out as a whole
+func visibleOnly(w http.ResponseWriter, r *http.Request, deck model.Deck) bool {
+	if !deck.VisibleTo(callerID(r)) {
+		http.Error(w, deckHiddenMessage, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// redact replaces every card by a face down placeholder, keeping the count
+func redact(cards []model.Card) []model.Card {
+	hidden := make([]model.Card, len(cards))
+	for i := range hidden {
+		hidden[i] = model.Card{Hidden: true}
+	}
+	return hidden
+}
+
+func (s *Server) pileResponse(deck model.Deck, pile model.Pile, caller string) PileSerializer {
+	cards := []model.Card{}
+	for _, code := range pile.Cards {
+		cards = append(cards, model.CardFromCode(code))
+	}
+	cards = s.spellOut(deck, cards)
+	if !pile.VisibleTo(caller) {
+		cards = redact(cards)
+	}
+
+	return PileSerializer{
+		DeckID:     pile.DeckID,
+		Name:       pile.Name,
+		Owner:      pile.Owner,
+		Visibility: pile.Visibility,
+		Count:      len(cards),
+		Cards:      cards,
+	}
+}
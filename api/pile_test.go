@@ -0,0 +1,192 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	api "toggl-test-wiliam/api"
+	model "toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPile_OwnerOnlyHand(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck?cards=AS,KD,QH,JC", "application/json", nil)
+	assert.NoError(t, err)
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	// Without authentication nobody could prove to own the hand
+	resp, _ = http.Post(testSuite.ts.URL+"/deck/"+deck.ID+"/pile?name=hand&visibility=owner&player=alice", "application/json", nil)
+	resp_body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "Owner visibility needs an authenticated caller\n", string(resp_body))
+
+	testSuite.server.APIKeys = playerKeys
+	resp = asPlayer(t, "POST", testSuite.ts.URL+"/deck/"+deck.ID+"/pile?name=hand&visibility=owner", "alice")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = asPlayer(t, "POST", testSuite.ts.URL+"/deck/"+deck.ID+"/pile?name=hand", "bob")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp = asPlayer(t, "POST", testSuite.ts.URL+"/deck/"+deck.ID+"/pile/hand/draw?count=2", "alice")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	hand := api.PileSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&hand))
+	assert.Equal(t, "alice", hand.Owner)
+	assert.Equal(t, model.VisibilityOwner, hand.Visibility)
+	assert.Equal(t, []string{"QH", "JC"}, model.CardCodes(hand.Cards))
+
+	// Anyone else only learns how many cards alice holds
+	resp = asPlayer(t, "GET", testSuite.ts.URL+"/deck/"+deck.ID+"/pile/hand?player=alice", "bob")
+	hand = api.PileSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&hand))
+	assert.Equal(t, 2, hand.Count)
+	assert.Equal(t, []model.Card{{Hidden: true}, {Hidden: true}}, hand.Cards)
+
+	resp = asPlayer(t, "GET", testSuite.ts.URL+"/deck/"+deck.ID+"/history", "bob")
+	history := api.HistorySerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&history))
+	assert.Equal(t, "hand", history.Events[1].Pile)
+	assert.Empty(t, history.Events[1].Cards)
+
+	// Undoing the draw takes the cards back out of the hand
+	resp = asPlayer(t, "POST", testSuite.ts.URL+"/deck/"+deck.ID+"/undo", "alice")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = asPlayer(t, "GET", testSuite.ts.URL+"/deck/"+deck.ID+"/piles", "alice")
+	piles := api.ListPilesSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&piles))
+	assert.Len(t, piles.Piles, 1)
+	assert.Empty(t, piles.Piles[0].Cards)
+
+	resp = asPlayer(t, "GET", testSuite.ts.URL+"/deck/"+deck.ID+"/pile/discard", "alice")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
+
+func TestOpenDeck_RedactsHiddenDecks(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, _ := http.Post(testSuite.ts.URL+"/deck?cards=AS,KD&owner=alice&visibility=owner", "application/json", nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	testSuite.server.APIKeys = playerKeys
+	resp = asPlayer(t, "POST", testSuite.ts.URL+"/deck?cards=AS,KD&visibility=owner", "alice")
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	resp = asPlayer(t, "GET", testSuite.ts.URL+"/deck/"+deck.ID, "alice")
+	opened := api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&opened))
+	assert.Equal(t, []string{"AS", "KD"}, model.CardCodes(opened.Cards))
+
+	// Claiming to be alice does not show bob her cards
+	resp = asPlayer(t, "GET", testSuite.ts.URL+"/deck/"+deck.ID+"?player=alice", "bob")
+	opened = api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&opened))
+	assert.Equal(t, 2, opened.Remaining)
+	assert.Equal(t, []model.Card{{Hidden: true}, {Hidden: true}}, opened.Cards)
+
+	for _, path := range []string{"?suit=SPADES", "/export"} {
+		resp = asPlayer(t, "GET", testSuite.ts.URL+"/deck/"+deck.ID+path, "bob")
+		resp_body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		assert.Equal(t, "Deck is not visible to you\n", string(resp_body))
+	}
+
+	resp = asPlayer(t, "POST", testSuite.ts.URL+"/deck?visibility=secret", "alice")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
+
+func TestSession_DrawsIntoHiddenHands(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	testSuite.server.APIKeys = playerKeys
+	resp := asPlayer(t, "POST", testSuite.ts.URL+"/session?seats=2", "alice")
+	session := api.SessionSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&session))
+	for _, player := range []string{"alice", "bob"} {
+		asPlayer(t, "POST", testSuite.ts.URL+"/session/"+session.ID+"/join", player)
+	}
+
+	resp = asPlayer(t, "POST", testSuite.ts.URL+"/session/"+session.ID+"/act?action=draw&count=5", "alice")
+	acted := api.SessionActionSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&acted))
+	assert.Len(t, acted.Cards, 5)
+
+	// The session deck is face down, and alice's hand is hers alone
+	resp = asPlayer(t, "GET", testSuite.ts.URL+"/deck/"+session.DeckIDs[0], "alice")
+	opened := api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&opened))
+	assert.Equal(t, 47, opened.Remaining)
+	assert.True(t, opened.Cards[0].Hidden)
+
+	resp = asPlayer(t, "GET", testSuite.ts.URL+"/deck/"+session.DeckIDs[0]+"/pile/hand:alice", "alice")
+	hand := api.PileSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&hand))
+	assert.Equal(t, acted.Cards, hand.Cards)
+
+	resp = asPlayer(t, "GET", testSuite.ts.URL+"/deck/"+session.DeckIDs[0]+"/pile/hand:alice", "bob")
+	hand = api.PileSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&hand))
+	assert.True(t, hand.Cards[0].Hidden)
+
+	testSuite.TearDownTest()
+}
+
+func TestSession_HandsCannotBeClaimed(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	testSuite.server.APIKeys = playerKeys
+	resp := asPlayer(t, "POST", testSuite.ts.URL+"/session?seats=2", "carol")
+	session := api.SessionSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&session))
+	for _, player := range []string{"bob", "carol"} {
+		asPlayer(t, "POST", testSuite.ts.URL+"/session/"+session.ID+"/join", player)
+	}
+	url := testSuite.ts.URL + "/deck/" + session.DeckIDs[0]
+
+	// carol may not set up bob's hand before his first draw
+	resp = asPlayer(t, "POST", url+"/pile?name=hand:bob&visibility=owner", "carol")
+	resp_body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "Deck belongs to session "+session.ID+"\n", string(resp_body))
+
+	// Nor does a hand of his held by someone else take his cards
+	testSuite.db.Create(&model.Pile{DeckID: session.DeckIDs[0], Name: "hand:bob", Owner: "carol", Visibility: model.VisibilityOwner, Cards: []string{}})
+	resp = asPlayer(t, "POST", testSuite.ts.URL+"/session/"+session.ID+"/act?action=draw", "bob")
+	resp_body, _ = ioutil.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "Hand belongs to carol\n", string(resp_body))
+	testSuite.db.Where("name = ?", "hand:bob").Delete(&model.Pile{})
+
+	resp = asPlayer(t, "POST", testSuite.ts.URL+"/session/"+session.ID+"/act?action=draw&count=2", "bob")
+	acted := api.SessionActionSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&acted))
+	assert.Len(t, acted.Cards, 2)
+
+	resp = asPlayer(t, "GET", url+"/pile/hand:bob", "bob")
+	hand := api.PileSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&hand))
+	assert.Equal(t, "bob", hand.Owner)
+	assert.Equal(t, acted.Cards, hand.Cards)
+
+	resp = asPlayer(t, "GET", url+"/pile/hand:bob", "carol")
+	hand = api.PileSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&hand))
+	assert.True(t, hand.Cards[0].Hidden)
+
+	testSuite.TearDownTest()
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"toggl-test-wiliam/model"
+)
+
+// RateLimit lets each client make Burst requests at once, refilled at Rate
+// requests per second; a zero Rate turns the limit off
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+var (
+	// DefaultCreateLimit throttles the requests that create decks
+	DefaultCreateLimit = RateLimit{Rate: 2, Burst: 30}
+	// DefaultDrawLimit throttles the requests that draw cards
+	DefaultDrawLimit = RateLimit{Rate: 10, Burst: 60}
+)
+
+// ParseRateLimit reads a limit written as "rate,burst", e.g. "2,30" for two
+// requests per second in bursts of up to thirty; "0" turns it off
+func ParseRateLimit(spec string) (RateLimit, error) {
+	rateParam, burstParam, found := strings.Cut(spec, ",")
+	rate, err := strconv.ParseFloat(rateParam, 64)
+	if err != nil || rate < 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, expected rate,burst", spec)
+	}
+	if !found {
+		if rate != 0 {
+			return RateLimit{}, fmt.Errorf("invalid rate limit %q, expected rate,burst", spec)
+		}
+		return RateLimit{}, nil
+	}
+
+	burst, err := strconv.Atoi(burstParam)
+	if err != nil || burst < 1 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, expected rate,burst", spec)
+	}
+	return RateLimit{Rate: rate, Burst: burst}, nil
+}
+
+// maxBuckets bounds how many clients a limiter tracks before it forgets
+// those whose bucket has refilled, and then those seen longest ago
+const maxBuckets = 10000
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter keeps a token bucket per client; it reads its limit on every
+// request, so that the server's limits may change after it started
+type rateLimiter struct {
+	limit *RateLimit
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func newRateLimiter(limit *RateLimit) *rateLimiter {
+	return &rateLimiter{limit: limit, buckets: map[string]*bucket{}}
+}
+
+// take spends a token of the client's bucket, or tells how long until one is back
+func (l *rateLimiter) take(client string, now time.Time) (bool, time.Duration) {
+	limit := *l.limit
+	if limit.Rate <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[client]
+	if !ok {
+		if len(l.buckets) >= maxBuckets {
+			l.forget(now)
+		}
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// forget drops the buckets that have refilled by now and, should that not
+// make room for another client, the least recently used ones
+func (l *rateLimiter) forget(now time.Time) {
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.limit.Rate >= float64(l.limit.Burst) {
+			delete(l.buckets, client)
+		}
+	}
+	if len(l.buckets) < maxBuckets {
+		return
+	}
+
+	clients := make([]string, 0, len(l.buckets))
+	for client := range l.buckets {
+		clients = append(clients, client)
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		return l.buckets[clients[i]].last.Before(l.buckets[clients[j]].last)
+	})
+	for _, client := range clients[:len(clients)-maxBuckets+1] {
+		delete(l.buckets, client)
+	}
+}
+
+// throttled answers 429 once the client ran out of requests on the limiter
+func (s *Server) throttled(limiter *rateLimiter, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ok, retryAfter := limiter.take(clientOf(r), s.Now()); !ok {
+			tooManyRequests(w, retryAfter, "Rate limit exceeded")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// creatingDecks throttles a handler that creates decks and keeps the
+// caller's tenant under its live deck quota
+func (s *Server) creatingDecks(next http.HandlerFunc) http.HandlerFunc {
+	return s.throttled(s.createLimiter, func(w http.ResponseWriter, r *http.Request) {
+		if s.underDeckQuota(w, r) {
+			next(w, r)
+		}
+	})
+}
+
+// underDeckQuota answers 429 when the tenant already holds as many live decks
+// as it may, asking to retry once the first of them expires
+func (s *Server) underDeckQuota(w http.ResponseWriter, r *http.Request) bool {
+	if err := s.deckQuota(tenantOf(r)); err != nil {
+		fail(w, err)
+		return false
+	}
+	return true
+}
+
+// deckQuota fails with 429 once the tenant holds as many live decks as it may
+func (s *Server) deckQuota(tenant string) error {
+	limits, err := s.limitsOf(tenant)
+	if err != nil || limits.LiveDecks == 0 {
+		return err
+	}
+
+	now := s.Now().UTC()
+	live := inTenant(s.DB.Model(&model.Deck{}), tenant).Where("(expires_at IS NULL OR expires_at > ?)", now)
+
+	var count int64
+	if err := live.Count(&count).Error; err != nil {
+		s.Logger.Printf("count live decks of tenant %q: %v", tenant, err)
+		return errDatabase
+	}
+	if model.Within(int(count)+1, limits.LiveDecks) {
+		return nil
+	}
+
+	// Decks without a TTL only go once drawn out, which the janitor checks every minute
+	retryAfter := time.Minute
+	var next model.Deck
+	err = inTenant(s.DB, tenant).Where("expires_at > ?", now).Order("expires_at").Limit(1).Find(&next).Error
+	if err == nil && next.ExpiresAt != nil {
+		retryAfter = next.ExpiresAt.Sub(now)
+	}
+	return &deckError{
+		status:     http.StatusTooManyRequests,
+		message:    model.ErrQuotaExceeded.Error() + ": too many live decks",
+		retryAfter: retryAfter,
+	}
+}
+
+func tooManyRequests(w http.ResponseWriter, retryAfter time.Duration, message string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, message, http.StatusTooManyRequests)
+}
+
+// clientOf names the client a bucket belongs to: the authenticated caller,
+// or the remote address while authentication is off
+func clientOf(r *http.Request) string {
+	return clientKey(principalIn(r.Context()), r.RemoteAddr)
+}
+
+func clientKey(caller Principal, remoteAddr string) string {
+	if caller.Name != "" {
+		return caller.Tenant + "/" + caller.Name
+	}
+
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
@@ -0,0 +1,162 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+	api "toggl-test-wiliam/api"
+	model "toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit_CreateDeck(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	testSuite.server.Now = func() time.Time { return now }
+	testSuite.server.CreateLimit = api.RateLimit{Rate: 0.5, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		resp, err := http.Post(testSuite.ts.URL+"/deck", "application/json", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck", "application/json", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "2", resp.Header.Get("Retry-After"))
+
+	// Game tables create decks too, so they take from the same bucket
+	resp, err = http.Post(testSuite.ts.URL+"/solitaire", "application/json", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+
+	now = now.Add(2 * time.Second)
+	resp, err = http.Post(testSuite.ts.URL+"/deck", "application/json", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
+
+func TestRateLimit_DrawPerClient(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+	testSuite.server.Now = func() time.Time { return time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC) }
+	testSuite.server.APIKeys = map[string]api.Principal{"alice-key": {Name: "alice"}, "bob-key": {Name: "bob"}}
+	testSuite.server.DrawLimit = api.RateLimit{Rate: 1, Burst: 1}
+
+	resp := authRequest(t, "POST", testSuite.ts.URL+"/deck", "X-API-Key", "alice-key")
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+	url := testSuite.ts.URL + "/deck/" + deck.ID + "/draw"
+
+	resp = authRequest(t, "GET", url, "X-API-Key", "alice-key")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = authRequest(t, "GET", url, "X-API-Key", "alice-key")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+
+	resp = authRequest(t, "GET", url, "X-API-Key", "bob-key")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
+
+func TestRateLimit_ShuffleDrawsFromDrawBucket(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+	testSuite.server.Now = func() time.Time { return time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC) }
+	testSuite.server.DrawLimit = api.RateLimit{Rate: 1, Burst: 1}
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck", "application/json", nil)
+	assert.NoError(t, err)
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	resp, err = http.Post(testSuite.ts.URL+"/deck/"+deck.ID+"/shuffle", "application/json", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, err = http.Post(testSuite.ts.URL+"/deck/"+deck.ID+"/shuffle", "application/json", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
+
+func TestRateLimit_GameActionsDrawFromDrawBucket(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+	testSuite.server.Now = func() time.Time { return time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC) }
+	testSuite.server.DrawLimit = api.RateLimit{Rate: 1, Burst: 1}
+
+	resp, err := http.Post(testSuite.ts.URL+"/solitaire", "application/json", nil)
+	assert.NoError(t, err)
+	game := api.SolitaireSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&game))
+	resp, err = http.Post(testSuite.ts.URL+"/blackjack?bet=10", "application/json", nil)
+	assert.NoError(t, err)
+	table := api.BlackjackSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&table))
+	resp, err = http.Post(testSuite.ts.URL+"/deck", "application/json", nil)
+	assert.NoError(t, err)
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	resp, err = http.Post(testSuite.ts.URL+"/solitaire/"+game.ID+"/draw", "application/json", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	for _, path := range []string{"/solitaire/" + game.ID + "/draw", "/solitaire/" + game.ID + "/move", "/blackjack/" + table.ID + "/hit", "/deck/" + deck.ID + "/undo"} {
+		resp, err = http.Post(testSuite.ts.URL+path, "application/json", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode, path)
+	}
+
+	testSuite.TearDownTest()
+}
+
+func TestRateLimit_LiveDecksPerTenant(t *testing.T) {
+	testSuite := setupTenants()
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	testSuite.server.Now = func() time.Time { return now }
+	testSuite.server.TenantQuota = model.Quota{LiveDecks: 2}
+
+	resp := tenantRequest(t, "POST", testSuite.ts.URL+"/deck?ttl=30s", "alice-key", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = tenantRequest(t, "POST", testSuite.ts.URL+"/deck?ttl=1h", "alice-key", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = tenantRequest(t, "POST", testSuite.ts.URL+"/deck", "alice-key", "")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "30", resp.Header.Get("Retry-After"))
+
+	// Other tenants keep their own allowance
+	resp = tenantRequest(t, "POST", testSuite.ts.URL+"/deck", "bob-key", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Expired decks no longer count
+	now = now.Add(30 * time.Second)
+	resp = tenantRequest(t, "POST", testSuite.ts.URL+"/deck", "alice-key", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
+
+func TestParseRateLimit(t *testing.T) {
+	limit, err := api.ParseRateLimit("2.5,30")
+	assert.NoError(t, err)
+	assert.Equal(t, api.RateLimit{Rate: 2.5, Burst: 30}, limit)
+
+	limit, err = api.ParseRateLimit("0")
+	assert.NoError(t, err)
+	assert.Equal(t, api.RateLimit{}, limit)
+
+	for _, spec := range []string{"2", "x,3", "2,0", "-1,5"} {
+		_, err = api.ParseRateLimit(spec)
+		assert.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+	"toggl-test-wiliam/assets"
+	"toggl-test-wiliam/model"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// DefaultDeckTTL is how long a deck lives when neither the server nor the
+// request configure a TTL
+const DefaultDeckTTL = 24 * time.Hour
+
+// Server holds the dependencies shared by every deck handler
+type Server struct {
+	DB         *gorm.DB
+	Now        func() time.Time
+	Rand       *rand.Rand
+	Logger     *log.Logger
+	DefaultTTL time.Duration
+
+	// ImageBaseURL prefixes the card image links, e.g. to point them at a CDN
+	ImageBaseURL string
+
+	// APIKeys maps each accepted "X-API-Key" to its holder, and JWTSecret
+	// verifies HS256 bearer tokens; with neither set, every request is let
+	// through anonymously
+	APIKeys   map[string]Principal
+	JWTSecret []byte
+
+	// TenantQuota limits tenants that have no quota of their own
+	TenantQuota model.Quota
+
+	// CreateLimit and DrawLimit throttle each client on the endpoints that
+	// create decks and on those that draw cards
+	CreateLimit RateLimit
+	DrawLimit   RateLimit
+
+	// WebhookRetries is how many times a failed webhook delivery is retried,
+	// WebhookBackoff how long to wait before the first retry, doubling after each
+	WebhookRetries int
+	WebhookBackoff time.Duration
+	WebhookClient  *http.Client
+	// WebhookAllowPrivate lets webhooks call loopback, link-local and private
+	// addresses, refused by default; meant for tests and local setups
+	WebhookAllowPrivate bool
+
+	router        *mux.Router
+	randMu        sync.Mutex
+	createLimiter *rateLimiter
+	drawLimiter   *rateLimiter
+	events        *eventHub
+}
+
+// NewServer wires the deck routes against the given database, using the
+// wall clock, a time-seeded RNG, a stderr logger, DefaultDeckTTL and
+// DefaultImageBaseURL as defaults
+func NewServer(db *gorm.DB) *Server {
+	s := &Server{
+		DB:         db,
+		Now:        time.Now,
+		Rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+		Logger:     log.New(os.Stderr, "", log.LstdFlags),
+		DefaultTTL: DefaultDeckTTL,
+		router:     mux.NewRouter(),
+
+		ImageBaseURL: DefaultImageBaseURL,
+		TenantQuota:  DefaultTenantQuota,
+		CreateLimit:  DefaultCreateLimit,
+		DrawLimit:    DefaultDrawLimit,
+
+		WebhookRetries: DefaultWebhookRetries,
+		WebhookBackoff: DefaultWebhookBackoff,
+	}
+	s.WebhookClient = &http.Client{Timeout: 10 * time.Second, Transport: s.webhookTransport()}
+	s.createLimiter = newRateLimiter(&s.CreateLimit)
+	s.drawLimiter = newRateLimiter(&s.DrawLimit)
+	s.events = newEventHub()
+	s.router.Use(s.authenticate)
+
+	// Serves the bundled artwork the card image links point at by default
+	s.router.PathPrefix("/static/cards/").Handler(http.StripPrefix("/static/", http.FileServer(http.FS(assets.Cards))))
+
+	s.router.HandleFunc("/deck", s.creatingDecks(s.CreateNewDeck)).Methods("POST")
+	s.router.HandleFunc("/deck", s.ListDecks).Methods("GET")
+	s.router.HandleFunc("/deck/import", s.creatingDecks(s.ImportDeck)).Methods("POST")
+	s.router.HandleFunc("/deck/{deck_id}", s.OpenDeck).Methods("GET")
+	s.router.HandleFunc("/deck/{deck_id}/draw", s.throttled(s.drawLimiter, s.DrawCards)).Methods("GET")
+	s.router.HandleFunc("/deck/{deck_id}/history", s.DeckHistory).Methods("GET")
+	s.router.HandleFunc("/deck/{deck_id}/events", s.DeckEvents).Methods("GET")
+	s.router.HandleFunc("/deck/{deck_id}/undo", s.throttled(s.drawLimiter, s.UndoLastOperation)).Methods("POST")
+	s.router.HandleFunc("/deck/{deck_id}/clone", s.creatingDecks(s.CloneDeck)).Methods("POST")
+	s.router.HandleFunc("/deck/{deck_id}/export", s.ExportDeck).Methods("GET")
+	s.router.HandleFunc("/deck/{deck_id}/sort", s.SortDeck).Methods("POST")
+	s.router.HandleFunc("/deck/{deck_id}/shuffle", s.throttled(s.drawLimiter, s.ShuffleDeck)).Methods("POST")
+	s.router.HandleFunc("/deck/{deck_id}/token", s.MintDeckToken).Methods("POST")
+	s.router.HandleFunc("/deck/{deck_id}/webhooks", s.CreateDeckWebhook).Methods("POST")
+	s.router.HandleFunc("/deck/{deck_id}/pile", s.CreatePile).Methods("POST")
+	s.router.HandleFunc("/deck/{deck_id}/piles", s.ListPiles).Methods("GET")
+	s.router.HandleFunc("/deck/{deck_id}/pile/{name}", s.OpenPile).Methods("GET")
+	s.router.HandleFunc("/deck/{deck_id}/pile/{name}/draw", s.throttled(s.drawLimiter, s.DrawToPile)).Methods("POST")
+	s.router.HandleFunc("/deck/{deck_id}/pile/{name}/sort", s.SortPile).Methods("POST")
+	s.router.HandleFunc("/evaluate/poker", s.EvaluatePoker).Methods("POST")
+	s.router.HandleFunc("/card-types", s.CreateCardType).Methods("POST")
+	s.router.HandleFunc("/card-types", s.ListCardTypes).Methods("GET")
+	s.router.HandleFunc("/tenant", s.OpenTenant).Methods("GET")
+	s.router.HandleFunc("/webhooks", s.CreateWebhook).Methods("POST")
+	s.router.HandleFunc("/webhooks", s.ListWebhooks).Methods("GET")
+	s.router.HandleFunc("/webhooks/deliveries", s.ListWebhookDeliveries).Methods("GET")
+	s.router.HandleFunc("/webhooks/{webhook_id}", s.DeleteWebhook).Methods("DELETE")
+
+	s.router.HandleFunc("/blackjack", s.creatingDecks(s.CreateBlackjackGame)).Methods("POST")
+	s.router.HandleFunc("/blackjack/{game_id}", s.OpenBlackjackGame).Methods("GET")
+	s.router.HandleFunc("/blackjack/{game_id}/deal", s.throttled(s.drawLimiter, s.blackjackAction(dealBlackjackRound))).Methods("POST")
+	s.router.HandleFunc("/blackjack/{game_id}/hit", s.throttled(s.drawLimiter, s.blackjackAction(hitBlackjack))).Methods("POST")
+	s.router.HandleFunc("/blackjack/{game_id}/stand", s.throttled(s.drawLimiter, s.blackjackAction(standBlackjack))).Methods("POST")
+	s.router.HandleFunc("/blackjack/{game_id}/double", s.throttled(s.drawLimiter, s.blackjackAction(doubleBlackjack))).Methods("POST")
+	s.router.HandleFunc("/blackjack/{game_id}/split", s.throttled(s.drawLimiter, s.blackjackAction(splitBlackjack))).Methods("POST")
+
+	s.router.HandleFunc("/table", s.throttled(s.createLimiter, s.CreateTable)).Methods("POST")
+	s.router.HandleFunc("/table/{table_id}", s.OpenTable).Methods("GET")
+	s.router.HandleFunc("/table/{table_id}/deal", s.creatingDecks(s.DealTableHand)).Methods("POST")
+	s.router.HandleFunc("/table/{table_id}/next", s.throttled(s.drawLimiter, s.NextStreet)).Methods("POST")
+	s.router.HandleFunc("/table/{table_id}/sit", s.SitAtTable).Methods("POST")
+
+	s.router.HandleFunc("/solitaire", s.creatingDecks(s.CreateSolitaireGame)).Methods("POST")
+	s.router.HandleFunc("/solitaire/{game_id}", s.OpenSolitaireGame).Methods("GET")
+	s.router.HandleFunc("/solitaire/{game_id}/draw", s.throttled(s.drawLimiter, s.solitaireAction(drawSolitaire))).Methods("POST")
+	s.router.HandleFunc("/solitaire/{game_id}/move", s.throttled(s.drawLimiter, s.solitaireAction(moveSolitaire))).Methods("POST")
+
+	s.router.HandleFunc("/session", s.creatingDecks(s.CreateSession)).Methods("POST")
+	s.router.HandleFunc("/session/{session_id}", s.OpenSession).Methods("GET")
+	s.router.HandleFunc("/session/{session_id}/join", s.JoinSession).Methods("POST")
+	s.router.HandleFunc("/session/{session_id}/leave", s.LeaveSession).Methods("POST")
+	s.router.HandleFunc("/session/{session_id}/act", s.throttled(s.drawLimiter, s.ActOnSession)).Methods("POST")
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
+}
+
+// shuffleSeed draws a seed from the shared RNG, which is not safe for concurrent use
+func (s *Server) shuffleSeed() int64 {
+	s.randMu.Lock()
+	defer s.randMu.Unlock()
+	return s.Rand.Int63()
+}
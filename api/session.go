@@ -0,0 +1,300 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"toggl-test-wiliam/model"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type SessionSerializer struct {
+	ID            string       `json:"session_id"`
+	MaxSeats      int          `json:"max_seats"`
+	Turn          int          `json:"turn"`
+	CurrentPlayer string       `json:"current_player,omitempty"`
+	DeckIDs       []string     `json:"deck_ids"`
+	Seats         []model.Seat `json:"seats"`
+}
+
+type SessionActionSerializer struct {
+	Session SessionSerializer `json:"session"`
+	Cards   []model.Card      `json:"cards,omitempty"`
+}
+
+// CreateSession opens a session over "decks" shuffled French decks with room for "seats" players
+func (s *Server) CreateSession(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	decks := 1
+	if decksParam := query.Get("decks"); decksParam != "" {
+		var err error
+		if decks, err = strconv.Atoi(decksParam); err != nil || decks < 1 || decks > model.MaxSessionDecks {
+			http.Error(w, "invalid decks: "+decksParam, http.StatusBadRequest)
+			return
+		}
+	}
+
+	seats := 4
+	if seatsParam := query.Get("seats"); seatsParam != "" {
+		var err error
+		if seats, err = strconv.Atoi(seatsParam); err != nil {
+			http.Error(w, "invalid seats: "+seatsParam, http.StatusBadRequest)
+			return
+		}
+	}
+
+	session, err := model.NewSession(seats, []string{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	session.Tenant = tenantOf(r)
+
+	expiresAt, err := s.expiresAt(query.Get("ttl"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var codes []string
+	s.DB.Model(&model.Card{}).Where("card_type = ?", "FRENCH").Pluck("code", &codes)
+
+	err = s.transaction(s.DB, func(tx *gorm.DB) error {
+		for i := 0; i < decks; i++ {
+			deck := model.Deck{}
+			deck, err := deck.Create(codes)
+			if err != nil {
+				return err
+			}
+			deck.SessionID = session.ID
+			stampCreator(r, &deck)
+			deck.Visibility = model.VisibilityFaceDown
+			deck.ExpiresAt = expiresAt
+
+			events := []model.DeckEvent{{Type: model.EventCreated, Cards: model.CardCodes(deck.Cards)}}
+			seed := s.shuffleSeed()
+			deck.ShuffleSeed(seed)
+			events = append(events, model.DeckEvent{Type: model.EventShuffled, Seed: seed})
+
+			if err := tx.Create(&deck).Error; err != nil {
+				return err
+			}
+			if err := s.recordEvents(tx, deck.ID, events...); err != nil {
+				return err
+			}
+			session.DeckIDs = append(session.DeckIDs, deck.ID)
+		}
+		return tx.Create(session).Error
+	})
+	if err != nil {
+		s.Logger.Printf("create session: %v", err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessionResponse(session))
+}
+
+// OpenSession shows the seats, the decks and whose turn it is
+func (s *Server) OpenSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.findSession(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessionResponse(session))
+}
+
+// JoinSession seats the caller after everyone already seated
+func (s *Server) JoinSession(w http.ResponseWriter, r *http.Request) {
+	s.seatAction(w, r, func(session *model.Session, player string) error {
+		return session.Join(player, s.Now().UTC())
+	})
+}
+
+// LeaveSession frees the seat of the caller
+func (s *Server) LeaveSession(w http.ResponseWriter, r *http.Request) {
+	s.seatAction(w, r, func(session *model.Session, player string) error {
+		return session.Leave(player)
+	})
+}
+
+// seatAction applies a change of seats for the caller and saves the session
+func (s *Server) seatAction(w http.ResponseWriter, r *http.Request, change func(*model.Session, string) error) {
+	player, ok := sessionPlayer(w, r)
+	if !ok {
+		return
+	}
+	session, ok := s.findSession(w, r)
+	if !ok {
+		return
+	}
+
+	if err := change(session, player); err != nil {
+		http.Error(w, err.Error(), sessionErrorStatus(err))
+		return
+	}
+	if err := s.DB.Save(session).Error; err != nil {
+		s.Logger.Printf("save session %s: %v", session.ID, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessionResponse(session))
+}
+
+// ActOnSession lets the player whose turn it is take an "action", then passes
+// the turn on: "draw" takes "count" cards off the session deck numbered
+// "deck" into the player's hand, "pass" does nothing
+func (s *Server) ActOnSession(w http.ResponseWriter, r *http.Request) {
+	player, ok := sessionPlayer(w, r)
+	if !ok {
+		return
+	}
+	session, ok := s.findSession(w, r)
+	if !ok {
+		return
+	}
+	if err := session.CheckTurn(player); err != nil {
+		http.Error(w, err.Error(), sessionErrorStatus(err))
+		return
+	}
+
+	query := r.URL.Query()
+	response := SessionActionSerializer{}
+
+	var deck model.Deck
+	var hand model.Pile
+	count := 0
+	switch action := query.Get("action"); action {
+	case "draw":
+		index, _ := strconv.Atoi(query.Get("deck"))
+		if index < 0 || index >= len(session.DeckIDs) {
+			http.Error(w, "invalid deck: "+query.Get("deck"), http.StatusBadRequest)
+			return
+		}
+		count, _ = strconv.Atoi(query.Get("count"))
+		if count == 0 {
+			count = 1
+		}
+
+		if deck, ok = s.loadDeck(w, r, session.DeckIDs[index]); !ok {
+			return
+		}
+		if count < 1 || count > len(deck.Cards) {
+			http.Error(w, "Not enough cards in the deck", http.StatusBadRequest)
+			return
+		}
+
+		err := s.DB.Where("deck_id = ? AND name = ?", deck.ID, handPile(player)).First(&hand).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			hand = model.Pile{DeckID: deck.ID, Name: handPile(player), Owner: player, Visibility: model.VisibilityOwner}
+		} else if err != nil {
+			s.Logger.Printf("load hand of %s in session %s: %v", player, session.ID, err)
+			http.Error(w, "database error", http.StatusInternalServerError)
+			return
+		}
+		if hand.Owner != player {
+			http.Error(w, "Hand belongs to "+hand.Owner, http.StatusConflict)
+			return
+		}
+	case "pass":
+	default:
+		http.Error(w, "invalid action: "+action, http.StatusBadRequest)
+		return
+	}
+
+	session.EndTurn()
+	err := s.transaction(s.DB, func(tx *gorm.DB) error {
+		if count > 0 {
+			before := len(hand.Cards)
+			if err := s.drawToPile(tx, &deck, &hand, count); err != nil {
+				return err
+			}
+			for _, code := range hand.Cards[before:] {
+				response.Cards = append(response.Cards, model.CardFromCode(code))
+			}
+		}
+		return tx.Save(session).Error
+	})
+	if err != nil {
+		s.Logger.Printf("save session %s: %v", session.ID, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	response.Session = sessionResponse(session)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handPile names the pile a session player's draws go to
+func handPile(player string) string {
+	return "hand:" + player
+}
+
+func (s *Server) findSession(w http.ResponseWriter, r *http.Request) (*model.Session, bool) {
+	session := &model.Session{}
+	if err := inTenant(s.DB, tenantOf(r)).First(session, "id = ?", mux.Vars(r)["session_id"]).Error; err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return nil, false
+	}
+	return session, true
+}
+
+// sessionPlayer is the acting player, who must be authenticated: turns and
+// hands bound to a name anyone could claim would enforce nothing
+func sessionPlayer(w http.ResponseWriter, r *http.Request) (string, bool) {
+	player := callerID(r)
+	if player == "" {
+		http.Error(w, "Taking part in a session needs an authenticated caller", http.StatusForbidden)
+		return "", false
+	}
+	return player, true
+}
+
+// outsideSession answers 409 for decks that only their session may change,
+// so that players cannot draw out of turn through the deck endpoints
+func outsideSession(w http.ResponseWriter, deck model.Deck) bool {
+	if err := sessionless(deck); err != nil {
+		fail(w, err)
+		return false
+	}
+	return true
+}
+
+// sessionless fails with 409 for a deck dealt by a session
+func sessionless(deck model.Deck) error {
+	if deck.SessionID != "" {
+		return &deckError{status: http.StatusConflict, message: fmt.Sprintf("Deck belongs to session %s", deck.SessionID)}
+	}
+	return nil
+}
+
+func sessionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, model.ErrNotSeated), errors.Is(err, model.ErrNotYourTurn):
+		return http.StatusForbidden
+	default:
+		return http.StatusConflict
+	}
+}
+
+func sessionResponse(session *model.Session) SessionSerializer {
+	return SessionSerializer{
+		ID:            session.ID,
+		MaxSeats:      session.MaxSeats,
+		Turn:          session.Turn,
+		CurrentPlayer: session.CurrentPlayer(),
+		DeckIDs:       session.DeckIDs,
+		Seats:         session.Seats,
+	}
+}
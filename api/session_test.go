@@ -0,0 +1,92 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	api "toggl-test-wiliam/api"
+	model "toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession_TakeTurns(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	testSuite.server.APIKeys = playerKeys
+	resp := asPlayer(t, "POST", testSuite.ts.URL+"/session?decks=2&seats=2", "alice")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	session := api.SessionSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&session))
+	assert.NotEmpty(t, session.ID)
+	assert.Len(t, session.DeckIDs, 2)
+	assert.Empty(t, session.Seats)
+
+	for _, player := range []string{"alice", "bob"} {
+		resp = asPlayer(t, "POST", testSuite.ts.URL+"/session/"+session.ID+"/join", player)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	resp = asPlayer(t, "POST", testSuite.ts.URL+"/session/"+session.ID+"/join", "carol")
+	resp_body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "session is full\n", string(resp_body))
+
+	// Only the player holding the turn can act
+	resp = asPlayer(t, "POST", testSuite.ts.URL+"/session/"+session.ID+"/act?player=alice&action=pass", "bob")
+	resp_body, _ = ioutil.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "not your turn\n", string(resp_body))
+
+	resp = asPlayer(t, "POST", testSuite.ts.URL+"/session/"+session.ID+"/act?action=draw&deck=1&count=3", "alice")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	acted := api.SessionActionSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&acted))
+	assert.Len(t, acted.Cards, 3)
+	assert.Equal(t, "bob", acted.Session.CurrentPlayer)
+
+	var stored model.Deck
+	testSuite.db.First(&stored, "id = ?", session.DeckIDs[1])
+	assert.Equal(t, 49, stored.Remaining)
+	assert.Equal(t, session.ID, stored.SessionID)
+
+	resp = asPlayer(t, "POST", testSuite.ts.URL+"/session/"+session.ID+"/leave", "bob")
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&session))
+	assert.Equal(t, "alice", session.CurrentPlayer)
+	assert.Len(t, session.Seats, 1)
+
+	testSuite.TearDownTest()
+}
+
+func TestSession_DecksOnlyDrawnThroughSession(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/session", "application/json", nil)
+	assert.NoError(t, err)
+
+	session := api.SessionSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&session))
+
+	resp, _ = http.Get(testSuite.ts.URL + "/deck/" + session.DeckIDs[0] + "/draw")
+	resp_body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "Deck belongs to session "+session.ID+"\n", string(resp_body))
+
+	resp, _ = http.Post(testSuite.ts.URL+"/session/"+session.ID+"/act?player=alice&action=pass", "application/json", nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Without authentication nobody can sit down, whatever player they claim
+	resp, _ = http.Post(testSuite.ts.URL+"/session/"+session.ID+"/join?player=alice", "application/json", nil)
+	resp_body, _ = ioutil.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "Taking part in a session needs an authenticated caller\n", string(resp_body))
+
+	resp, _ = http.Get(testSuite.ts.URL + "/session/unknown")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
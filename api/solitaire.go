@@ -0,0 +1,186 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"toggl-test-wiliam/game/solitaire"
+	"toggl-test-wiliam/model"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type SolitaireColumnSerializer struct {
+	Hidden int      `json:"hidden"`
+	Cards  []string `json:"cards"`
+}
+
+type SolitaireSerializer struct {
+	ID          string                      `json:"game_id"`
+	DrawCount   int                         `json:"draw_count"`
+	Status      string                      `json:"status"`
+	Moves       int                         `json:"moves"`
+	Passes      int                         `json:"passes"`
+	Stock       int                         `json:"stock"`
+	Waste       []string                    `json:"waste"`
+	Foundations []solitaire.Foundation      `json:"foundations"`
+	Tableau     []SolitaireColumnSerializer `json:"tableau"`
+}
+
+// CreateSolitaireGame shuffles a French deck and deals a Klondike layout from it
+func (s *Server) CreateSolitaireGame(w http.ResponseWriter, r *http.Request) {
+	drawCount := 1
+	if drawParam := r.URL.Query().Get("draw"); drawParam != "" {
+		var err error
+		if drawCount, err = strconv.Atoi(drawParam); err != nil {
+			http.Error(w, "invalid draw: "+drawParam, http.StatusBadRequest)
+			return
+		}
+	}
+
+	var codes []string
+	s.DB.Model(&model.Card{}).Where("card_type = ?", "FRENCH").Pluck("code", &codes)
+
+	deck := model.Deck{}
+	deck, err := deck.Create(codes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	deck.ExpiresAt, _ = s.expiresAt("")
+	stampCreator(r, &deck)
+
+	events := []model.DeckEvent{{Type: model.EventCreated, Cards: model.CardCodes(deck.Cards)}}
+	seed := s.shuffleSeed()
+	deck.ShuffleSeed(seed)
+	events = append(events, model.DeckEvent{Type: model.EventShuffled, Seed: seed})
+
+	game, err := solitaire.NewGame(deck.ID, drawCount)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	game.Tenant = deck.Tenant
+	// The deck only plays through the game, which keeps the stock and the
+	// face down tableau from being read off it
+	deck.GameID = game.ID
+	deck.Visibility = model.VisibilityFaceDown
+	draw, drawn := drawFrom(&deck, solitaire.ErrDeckTooSmall)
+	if err := game.Deal(draw); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.transaction(s.DB, func(tx *gorm.DB) error {
+		if err := tx.Create(&deck).Error; err != nil {
+			return err
+		}
+		if err := s.recordEvents(tx, deck.ID, append(events, *drawn...)...); err != nil {
+			return err
+		}
+		return tx.Create(game).Error
+	})
+	if err != nil {
+		s.Logger.Printf("create solitaire game: %v", err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(solitaireResponse(game))
+}
+
+// OpenSolitaireGame shows the layout, keeping the stock and face down tableau cards hidden
+func (s *Server) OpenSolitaireGame(w http.ResponseWriter, r *http.Request) {
+	game, ok := s.findSolitaireGame(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(solitaireResponse(game))
+}
+
+// solitaireMove is a player move on a game
+type solitaireMove func(r *http.Request, game *solitaire.Game) error
+
+// solitaireAction applies a player move and saves the resulting layout
+func (s *Server) solitaireAction(move solitaireMove) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		game, ok := s.findSolitaireGame(w, r)
+		if !ok {
+			return
+		}
+
+		if err := move(r, game); err != nil {
+			status := http.StatusConflict
+			if errors.Is(err, solitaire.ErrUnknownPile) || errors.Is(err, errInvalidCount) {
+				status = http.StatusBadRequest
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		if err := s.DB.Save(game).Error; err != nil {
+			s.Logger.Printf("save solitaire game %s: %v", game.ID, err)
+			http.Error(w, "database error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(solitaireResponse(game))
+	}
+}
+
+var errInvalidCount = errors.New("count must be a positive integer")
+
+func drawSolitaire(r *http.Request, game *solitaire.Game) error {
+	return game.Draw()
+}
+
+// moveSolitaire moves "count" cards, 1 by default, from the "from" pile to the "to" pile
+func moveSolitaire(r *http.Request, game *solitaire.Game) error {
+	query := r.URL.Query()
+
+	count := 1
+	if countParam := query.Get("count"); countParam != "" {
+		var err error
+		if count, err = strconv.Atoi(countParam); err != nil || count < 1 {
+			return errInvalidCount
+		}
+	}
+	return game.Move(query.Get("from"), query.Get("to"), count)
+}
+
+func (s *Server) findSolitaireGame(w http.ResponseWriter, r *http.Request) (*solitaire.Game, bool) {
+	game := &solitaire.Game{}
+	if err := inTenant(s.DB, tenantOf(r)).First(game, "id = ?", mux.Vars(r)["game_id"]).Error; err != nil {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return nil, false
+	}
+	return game, true
+}
+
+func solitaireResponse(game *solitaire.Game) SolitaireSerializer {
+	response := SolitaireSerializer{
+		ID:          game.ID,
+		DrawCount:   game.DrawCount,
+		Status:      game.Status,
+		Moves:       game.Moves,
+		Passes:      game.Passes,
+		Stock:       len(game.Stock),
+		Waste:       game.Waste,
+		Foundations: game.Foundations,
+		Tableau:     []SolitaireColumnSerializer{},
+	}
+
+	for _, column := range game.Tableau {
+		response.Tableau = append(response.Tableau, SolitaireColumnSerializer{
+			Hidden: column.Hidden,
+			Cards:  column.Cards[column.Hidden:],
+		})
+	}
+	return response
+}
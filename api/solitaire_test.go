@@ -0,0 +1,108 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	api "toggl-test-wiliam/api"
+	"toggl-test-wiliam/game/solitaire"
+	model "toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSolitaire_DealAndDraw(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/solitaire?draw=3", "application/json", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	game := api.SolitaireSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&game))
+	assert.NotEmpty(t, game.ID)
+	assert.Equal(t, solitaire.StatusPlaying, game.Status)
+	assert.Equal(t, 3, game.DrawCount)
+	assert.Equal(t, 24, game.Stock)
+	assert.Len(t, game.Foundations, 4)
+
+	// Only the face up card of each column is shown
+	assert.Len(t, game.Tableau, 7)
+	for col, column := range game.Tableau {
+		assert.Equal(t, col, column.Hidden)
+		assert.Len(t, column.Cards, 1)
+	}
+
+	resp, err = http.Post(testSuite.ts.URL+"/solitaire/"+game.ID+"/draw", "application/json", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(testSuite.ts.URL + "/solitaire/" + game.ID)
+	assert.NoError(t, err)
+	game = api.SolitaireSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&game))
+	assert.Equal(t, 21, game.Stock)
+	assert.Len(t, game.Waste, 3)
+	assert.Equal(t, 1, game.Moves)
+
+	testSuite.TearDownTest()
+}
+
+func TestSolitaire_InvalidMoves(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, _ := http.Post(testSuite.ts.URL+"/solitaire?draw=2", "application/json", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = http.Post(testSuite.ts.URL+"/solitaire", "application/json", nil)
+	game := api.SolitaireSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&game))
+
+	// The waste is empty until the first draw
+	resp, _ = http.Post(testSuite.ts.URL+"/solitaire/"+game.ID+"/move?from=waste&to=tableau:0", "application/json", nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp, _ = http.Post(testSuite.ts.URL+"/solitaire/"+game.ID+"/move?from=hand&to=tableau:0", "application/json", nil)
+	resp_body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "unknown pile: hand\n", string(resp_body))
+
+	resp, _ = http.Post(testSuite.ts.URL+"/solitaire/"+game.ID+"/move?from=tableau:6&to=tableau:0&count=x", "application/json", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = http.Get(testSuite.ts.URL + "/solitaire/unknown")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
+
+func TestSolitaire_DeckIsHidden(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/solitaire", "application/json", nil)
+	assert.NoError(t, err)
+	game := api.SolitaireSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&game))
+
+	deck := model.Deck{}
+	assert.NoError(t, testSuite.db.First(&deck).Error)
+	assert.Equal(t, game.ID, deck.GameID)
+	assert.Equal(t, model.VisibilityFaceDown, deck.Visibility)
+
+	// The history would tell the stock order and the face down tableau
+	for _, path := range []string{"", "/history", "/draw", "/export"} {
+		resp, _ = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + path)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, path)
+	}
+
+	resp, _ = http.Get(testSuite.ts.URL + "/deck")
+	list := api.ListDecksSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	assert.Empty(t, list.Decks)
+
+	testSuite.TearDownTest()
+}
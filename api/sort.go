@@ -0,0 +1,155 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"toggl-test-wiliam/model"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// sortOrder is how the sort endpoints were asked to order cards
+type sortOrder struct {
+	by      string
+	keys    []string
+	rules   string
+	ruleset model.Ruleset
+}
+
+// parseSortOrder reads the keys in the "by" query parameter, e.g.
+// "suit,rank", and the "rules" parameter ranking aces
+func parseSortOrder(r *http.Request) (sortOrder, error) {
+	order := sortOrder{by: r.URL.Query().Get("by"), rules: r.URL.Query().Get("rules")}
+	if order.by == "" {
+		order.by = "suit,rank"
+	}
+	order.keys = strings.Split(order.by, ",")
+	if err := model.ValidateSortKeys(order.keys); err != nil {
+		return order, err
+	}
+
+	if order.rules == "" {
+		order.rules = "ace_high"
+	}
+	var ok bool
+	if order.ruleset, ok = model.LookupRules(order.rules); !ok {
+		return order, fmt.Errorf("invalid rules: %s", order.rules)
+	}
+	return order, nil
+}
+
+// event records the sort of the deck, or of one of its piles
+func (o sortOrder) event(pile string) model.DeckEvent {
+	return model.DeckEvent{Type: model.EventSorted, SortBy: o.by, Rules: o.rules, Pile: pile}
+}
+
+// SortDeck reorders the remaining cards by the keys in the "by" query
+// parameter, e.g. "suit,rank", ranking aces with the "rules" parameter
+func (s *Server) SortDeck(w http.ResponseWriter, r *http.Request) {
+	deck, ok := s.findDeck(w, r, model.RoleDealer)
+	if !ok || !outsideSession(w, deck) {
+		return
+	}
+
+	order, err := parseSortOrder(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	deck.Sort(order.keys, order.ruleset)
+
+	err = s.transaction(s.DB, func(tx *gorm.DB) error {
+		if err := tx.Save(&deck).Error; err != nil {
+			return err
+		}
+		return s.recordEvents(tx, deck.ID, order.event(""))
+	})
+	if err != nil {
+		s.Logger.Printf("save deck %s: %v", deck.ID, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := OpenDeckSerializer{
+		ID:        deck.ID,
+		Shuffled:  deck.Shuffled,
+		Remaining: len(deck.Cards),
+		ExpiresAt: deck.ExpiresAt,
+		Cards:     deck.Cards,
+	}
+	if !deck.VisibleTo(callerID(r)) {
+		response.Cards = redact(deck.Cards)
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// SortPile reorders the cards of a pile, such as a hand, like SortDeck does
+// the deck. Only those who may see the cards of the pile may sort them
+func (s *Server) SortPile(w http.ResponseWriter, r *http.Request) {
+	deck, ok := s.findDeck(w, r, model.RoleDealer)
+	if !ok || !outsideSession(w, deck) {
+		return
+	}
+	pile, ok := s.findPile(w, deck.ID, mux.Vars(r)["name"])
+	if !ok {
+		return
+	}
+	if !pile.VisibleTo(callerID(r)) {
+		http.Error(w, "Pile "+pile.Name+" is not visible to you", http.StatusForbidden)
+		return
+	}
+
+	order, err := parseSortOrder(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pile.Sort(order.keys, order.ruleset)
+
+	err = s.transaction(s.DB, func(tx *gorm.DB) error {
+		if err := tx.Save(&pile).Error; err != nil {
+			return err
+		}
+		return s.recordEvents(tx, deck.ID, order.event(pile.Name))
+	})
+	if err != nil {
+		s.Logger.Printf("save pile %s of deck %s: %v", pile.Name, deck.ID, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.pileResponse(deck, pile, callerID(r)))
+}
+
+// parseCardFilter reads the suit, color, rank_gte and rank_lte query parameters
+func parseCardFilter(r *http.Request) (model.CardFilter, error) {
+	query := r.URL.Query()
+	filter := model.CardFilter{
+		Suit:  strings.ToUpper(query.Get("suit")),
+		Color: strings.ToUpper(query.Get("color")),
+	}
+
+	for param, target := range map[string]*int{
+		"rank_gte": &filter.RankGTE,
+		"rank_lte": &filter.RankLTE,
+	} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+
+		rank, err := strconv.Atoi(value)
+		if err != nil || rank < 1 || rank > 14 {
+			return filter, fmt.Errorf("invalid %s: %s", param, value)
+		}
+		*target = rank
+	}
+	return filter, nil
+}
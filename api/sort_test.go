@@ -0,0 +1,155 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	api "toggl-test-wiliam/api"
+	model "toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortDeck(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck?shuffle=true", "application/json", nil)
+	assert.NoError(t, err)
+
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	resp, err = http.Post(testSuite.ts.URL+"/deck/"+deck.ID+"/sort?by=suit,rank", "application/json", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	sorted := api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&sorted))
+	assert.False(t, sorted.Shuffled)
+	assert.Equal(t, []string{"2C", "3C", "4C"}, model.CardCodes(sorted.Cards[:3]))
+	assert.Equal(t, []string{"QS", "KS", "AS"}, model.CardCodes(sorted.Cards[49:]))
+
+	// The sort is part of the history, so replaying it yields the same order
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "?at=3")
+	assert.NoError(t, err)
+
+	replayed := api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&replayed))
+	assert.Equal(t, sorted.Cards, replayed.Cards)
+
+	resp, _ = http.Post(testSuite.ts.URL+"/deck/"+deck.ID+"/sort?by=colour", "application/json", nil)
+	resp_body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, "invalid sort key: colour\n", string(resp_body))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
+
+func TestShuffleDeck(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck", "application/json", nil)
+	assert.NoError(t, err)
+
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	resp, err = http.Post(testSuite.ts.URL+"/deck/"+deck.ID+"/shuffle", "application/json", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	shuffled := api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&shuffled))
+	assert.True(t, shuffled.Shuffled)
+	assert.Equal(t, 52, shuffled.Remaining)
+
+	// The shuffle is part of the history, so replaying it yields the same order
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "?at=2")
+	assert.NoError(t, err)
+
+	replayed := api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&replayed))
+	assert.Equal(t, shuffled.Cards, replayed.Cards)
+
+	testSuite.TearDownTest()
+}
+
+func TestOpenDeck_WithFilter(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck", "application/json", nil)
+	assert.NoError(t, err)
+
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "?suit=HEARTS&rank_gte=10")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	opened := api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&opened))
+	assert.Equal(t, 52, opened.Remaining)
+	assert.ElementsMatch(t, []string{"10H", "JH", "QH", "KH", "AH"}, model.CardCodes(opened.Cards))
+
+	resp, _ = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "?rank_gte=high")
+	resp_body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, "invalid rank_gte: high\n", string(resp_body))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
+
+func TestSortPile(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck?cards=2C,AS,KH,3D,QS", "application/json", nil)
+	assert.NoError(t, err)
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+	url := testSuite.ts.URL + "/deck/" + deck.ID
+
+	http.Post(url+"/pile?name=hand", "application/json", nil)
+	http.Post(url+"/pile/hand/draw?count=4", "application/json", nil)
+
+	resp, err = http.Post(url+"/pile/hand/sort?by=-rank", "application/json", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	hand := api.PileSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&hand))
+	assert.Equal(t, []string{"AS", "KH", "QS", "3D"}, model.CardCodes(hand.Cards))
+
+	// The deck itself is left alone
+	resp, _ = http.Get(url)
+	opened := api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&opened))
+	assert.Equal(t, []string{"2C"}, model.CardCodes(opened.Cards))
+
+	// Undoing the sort gives the hand its drawn order back
+	resp, _ = http.Post(url+"/undo", "application/json", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, _ = http.Get(url + "/pile/hand")
+	hand = api.PileSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&hand))
+	assert.Equal(t, []string{"AS", "KH", "3D", "QS"}, model.CardCodes(hand.Cards))
+
+	resp, _ = http.Post(url+"/pile/hand/sort?by=colour", "application/json", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, _ = http.Post(url+"/pile/unknown/sort", "application/json", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Nobody may sort a hand they cannot see
+	testSuite.server.APIKeys = playerKeys
+	asPlayer(t, "POST", url+"/pile?name=secret&visibility=owner", "alice")
+	resp = asPlayer(t, "POST", url+"/pile/secret/sort", "bob")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = asPlayer(t, "POST", url+"/pile/secret/sort", "alice")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
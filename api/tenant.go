@@ -0,0 +1,284 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"toggl-test-wiliam/model"
+
+	"gorm.io/gorm"
+)
+
+// DefaultTenantQuota applies to every tenant without a row of its own
+var DefaultTenantQuota = model.Quota{CardTypes: 10, TypeCards: 200, LiveDecks: 10000}
+
+type CardTypeSerializer struct {
+	CardType string `json:"card_type"`
+	Custom   bool   `json:"custom"`
+	Count    int    `json:"count"`
+}
+
+type ListCardTypesSerializer struct {
+	CardTypes []CardTypeSerializer `json:"card_types"`
+}
+
+type TenantUsageSerializer struct {
+	CardTypes int   `json:"card_types"`
+	Decks     int64 `json:"decks"`
+}
+
+type TenantSerializer struct {
+	Tenant string                `json:"tenant"`
+	Quota  model.Quota           `json:"quota"`
+	Usage  TenantUsageSerializer `json:"usage"`
+}
+
+// CreateCardTypeRequest describes a custom card type by its cards, whose
+// codes must be unique within the type
+type CreateCardTypeRequest struct {
+	CardType string       `json:"card_type"`
+	Cards    []model.Card `json:"cards"`
+}
+
+// CreateCardType adds a card type of the caller's tenant to the catalog
+func (s *Server) CreateCardType(w http.ResponseWriter, r *http.Request) {
+	req := CreateCardTypeRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cardType := strings.ToUpper(strings.TrimSpace(req.CardType))
+	if cardType == "" {
+		http.Error(w, "missing card_type", http.StatusBadRequest)
+		return
+	}
+	if err := validateCustomCards(req.Cards); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The default tenant is shared by every caller without one, so it only
+	// holds the built-in card types
+	tenant := tenantOf(r)
+	if tenant == "" {
+		http.Error(w, "Custom card types need a tenant", http.StatusForbidden)
+		return
+	}
+	usage, err := s.customCardTypes(tenant)
+	if err != nil {
+		s.Logger.Printf("count card types of tenant %q: %v", tenant, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	limits, ok := s.tenantLimits(w, tenant)
+	if !ok {
+		return
+	}
+	if !model.Within(usage+1, limits.CardTypes) || !model.Within(len(req.Cards), limits.TypeCards) {
+		http.Error(w, model.ErrQuotaExceeded.Error(), http.StatusForbidden)
+		return
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		// Names are unique among the card types a tenant sees, shared ones included
+		var existing int64
+		err := s.catalog(tx, tenant).Where("card_type = ?", cardType).Count(&existing).Error
+		if err != nil {
+			return err
+		}
+		if existing > 0 {
+			return errCardTypeExists
+		}
+
+		cards := make([]model.Card, len(req.Cards))
+		for i, card := range req.Cards {
+			cards[i] = model.Card{Value: card.Value, Suit: card.Suit, Code: card.Code, CardType: cardType, Tenant: tenant}
+		}
+		return tx.Create(&cards).Error
+	})
+	if errors.Is(err, errCardTypeExists) {
+		http.Error(w, "Card type already exists", http.StatusConflict)
+		return
+	} else if err != nil {
+		s.Logger.Printf("create card type %s: %v", cardType, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CardTypeSerializer{CardType: cardType, Custom: true, Count: len(req.Cards)})
+}
+
+var errCardTypeExists = errors.New("card type already exists")
+
+// ListCardTypes shows the shared card types and those of the caller's tenant
+func (s *Server) ListCardTypes(w http.ResponseWriter, r *http.Request) {
+	var rows []struct {
+		CardType string
+		Count    int
+	}
+	err := s.catalog(s.DB, tenantOf(r)).
+		Select("card_type, COUNT(*) AS count").
+		Group("card_type").
+		Order("card_type").
+		Scan(&rows).Error
+	if err != nil {
+		s.Logger.Printf("list card types: %v", err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	response := ListCardTypesSerializer{CardTypes: []CardTypeSerializer{}}
+	for _, row := range rows {
+		response.CardTypes = append(response.CardTypes, CardTypeSerializer{
+			CardType: row.CardType,
+			Custom:   row.CardType != "FRENCH",
+			Count:    row.Count,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// OpenTenant shows the caller's tenant with its quota and what it uses of it
+func (s *Server) OpenTenant(w http.ResponseWriter, r *http.Request) {
+	tenant := tenantOf(r)
+	limits, ok := s.tenantLimits(w, tenant)
+	if !ok {
+		return
+	}
+
+	response := TenantSerializer{Tenant: tenant, Quota: limits}
+	var err error
+	if response.Usage.CardTypes, err = s.customCardTypes(tenant); err == nil {
+		err = inTenant(s.DB.Model(&model.Deck{}), tenant).Count(&response.Usage.Decks).Error
+	}
+	if err != nil {
+		s.Logger.Printf("load usage of tenant %q: %v", tenant, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) tenantLimits(w http.ResponseWriter, id string) (model.Quota, bool) {
+	limits, err := s.limitsOf(id)
+	if err != nil {
+		fail(w, err)
+		return model.Quota{}, false
+	}
+	return limits, true
+}
+
+func (s *Server) limitsOf(id string) (model.Quota, error) {
+	tenant, err := model.LoadTenant(s.DB, id)
+	if err != nil {
+		s.Logger.Printf("load tenant %q: %v", id, err)
+		return model.Quota{}, errDatabase
+	}
+	return tenant.Limits(s.TenantQuota), nil
+}
+
+func (s *Server) customCardTypes(tenant string) (int, error) {
+	var count int64
+	err := s.DB.Model(&model.Card{}).
+		Where("COALESCE(tenant, '') = ? AND card_type <> ?", tenant, "FRENCH").
+		Distinct("card_type").
+		Count(&count).Error
+	return int(count), err
+}
+
+func validateCustomCards(cards []model.Card) error {
+	if len(cards) == 0 {
+		return errors.New("a card type needs at least one card")
+	}
+
+	codes := map[string]bool{}
+	for _, card := range cards {
+		if card.Code == "" || card.Value == "" {
+			return errors.New("every card needs a code and a value")
+		}
+		if strings.ContainsAny(card.Code, ", ") {
+			return fmt.Errorf("invalid card code: %q", card.Code)
+		}
+		if codes[card.Code] {
+			return fmt.Errorf("duplicate card code: %s", card.Code)
+		}
+		codes[card.Code] = true
+	}
+	return nil
+}
+
+// inTenant narrows a query down to the rows of the tenant
+func inTenant(tx *gorm.DB, tenant string) *gorm.DB {
+	return tx.Where("COALESCE(tenant, '') = ?", tenant)
+}
+
+// catalog queries the cards a tenant may build decks from: the shared ones
+// and its own custom card types
+func (s *Server) catalog(tx *gorm.DB, tenant string) *gorm.DB {
+	return tx.Model(&model.Card{}).Where("COALESCE(tenant, '') IN ?", []string{"", tenant})
+}
+
+// stampCreator records who created the deck and the tenant it belongs to
+func stampCreator(r *http.Request, deck *model.Deck) {
+	principalIn(r.Context()).stamp(deck)
+}
+
+// newDeck builds a deck of the card type from catalog codes, spelling out
+// custom cards from the caller's catalog
+func (s *Server) newDeck(tenant, cardType string, codes []string) (model.Deck, error) {
+	deck := model.Deck{}
+	if cardType == "" || cardType == "FRENCH" {
+		return deck.Create(codes)
+	}
+
+	faces, err := s.cardFaces(cardType, tenant)
+	if err != nil {
+		return deck, err
+	}
+	cards := make([]model.Card, 0, len(codes))
+	for _, code := range codes {
+		cards = append(cards, faces[code])
+	}
+	return deck.CreateOfType(cardType, cards)
+}
+
+// cardFaces maps the codes of a custom card type to its cards
+func (s *Server) cardFaces(cardType, tenant string) (map[string]model.Card, error) {
+	var cards []model.Card
+	if err := s.catalog(s.DB, tenant).Where("card_type = ?", cardType).Find(&cards).Error; err != nil {
+		return nil, err
+	}
+
+	faces := map[string]model.Card{}
+	for _, card := range cards {
+		faces[card.Code] = card
+	}
+	return faces, nil
+}
+
+// spellOut fills in the value and suit of custom cards rebuilt from their
+// codes alone, such as replayed or piled ones; French cards already are
+func (s *Server) spellOut(deck model.Deck, cards []model.Card) []model.Card {
+	if deck.CardType == "" || deck.CardType == "FRENCH" {
+		return cards
+	}
+
+	faces, err := s.cardFaces(deck.CardType, deck.Tenant)
+	if err != nil {
+		s.Logger.Printf("load card type %s: %v", deck.CardType, err)
+		return cards
+	}
+	spelled := make([]model.Card, len(cards))
+	for i, card := range cards {
+		spelled[i] = faces[card.Code]
+	}
+	return spelled
+}
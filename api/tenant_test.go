@@ -0,0 +1,199 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	api "toggl-test-wiliam/api"
+	model "toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tenantRequest(t *testing.T, method, url, key, body string) *http.Response {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	assert.NoError(t, err)
+	req.Header.Set("X-API-Key", key)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	return resp
+}
+
+func setupTenants() *APITestSuite {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+	testSuite.server.APIKeys = map[string]api.Principal{
+		"alice-key": {Name: "alice", Tenant: "studio-a"},
+		"bob-key":   {Name: "bob", Tenant: "studio-b"},
+	}
+	return testSuite
+}
+
+const unoCards = `{"card_type": "uno", "cards": [
+	{"code": "R1", "value": "1", "suit": "RED"},
+	{"code": "R2", "value": "2", "suit": "RED"},
+	{"code": "WILD", "value": "WILD"}
+]}`
+
+func TestTenant_DecksAreIsolated(t *testing.T) {
+	testSuite := setupTenants()
+
+	resp := tenantRequest(t, "POST", testSuite.ts.URL+"/deck", "alice-key", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	resp = tenantRequest(t, "GET", testSuite.ts.URL+"/deck/"+deck.ID, "bob-key", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = tenantRequest(t, "GET", testSuite.ts.URL+"/deck/"+deck.ID+"/draw", "bob-key", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	list := api.ListDecksSerializer{}
+	resp = tenantRequest(t, "GET", testSuite.ts.URL+"/deck", "bob-key", "")
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	assert.Empty(t, list.Decks)
+
+	resp = tenantRequest(t, "GET", testSuite.ts.URL+"/deck", "alice-key", "")
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	assert.Len(t, list.Decks, 1)
+
+	stored := model.Deck{}
+	testSuite.db.First(&stored, "id = ?", deck.ID)
+	assert.Equal(t, "studio-a", stored.Tenant)
+
+	testSuite.TearDownTest()
+}
+
+func TestTenant_CustomCardType(t *testing.T) {
+	testSuite := setupTenants()
+
+	resp := tenantRequest(t, "POST", testSuite.ts.URL+"/card-types", "alice-key", unoCards)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	created := api.CardTypeSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	assert.Equal(t, api.CardTypeSerializer{CardType: "UNO", Custom: true, Count: 3}, created)
+
+	resp = tenantRequest(t, "POST", testSuite.ts.URL+"/card-types", "alice-key", unoCards)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp = tenantRequest(t, "POST", testSuite.ts.URL+"/card-types", "alice-key", `{"card_type": "french", "cards": [{"code": "X", "value": "X"}]}`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	types := api.ListCardTypesSerializer{}
+	resp = tenantRequest(t, "GET", testSuite.ts.URL+"/card-types", "alice-key", "")
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&types))
+	assert.Equal(t, []api.CardTypeSerializer{
+		{CardType: "FRENCH", Count: 52},
+		{CardType: "UNO", Custom: true, Count: 3},
+	}, types.CardTypes)
+
+	resp = tenantRequest(t, "GET", testSuite.ts.URL+"/card-types", "bob-key", "")
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&types))
+	assert.Equal(t, []api.CardTypeSerializer{{CardType: "FRENCH", Count: 52}}, types.CardTypes)
+
+	resp = tenantRequest(t, "POST", testSuite.ts.URL+"/deck", "bob-key", `{"card_type": "UNO"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = tenantRequest(t, "POST", testSuite.ts.URL+"/deck", "alice-key", `{"card_type": "UNO", "cards": ["WILD", "R2", "R1"]}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+	assert.Equal(t, 3, deck.Remaining)
+
+	resp = tenantRequest(t, "GET", testSuite.ts.URL+"/deck/"+deck.ID+"/draw", "alice-key", "")
+	var drawn []model.Card
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&drawn))
+	assert.Equal(t, []model.Card{{Value: "1", Suit: "RED", Code: "R1"}}, drawn)
+
+	// Undoing rebuilds the cards from their codes alone
+	tenantRequest(t, "POST", testSuite.ts.URL+"/deck/"+deck.ID+"/undo", "alice-key", "")
+	resp = tenantRequest(t, "GET", testSuite.ts.URL+"/deck/"+deck.ID, "alice-key", "")
+	opened := api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&opened))
+	assert.Equal(t, "UNO", opened.CardType)
+	assert.Equal(t, []model.Card{
+		{Value: "WILD", Code: "WILD"},
+		{Value: "2", Suit: "RED", Code: "R2"},
+		{Value: "1", Suit: "RED", Code: "R1"},
+	}, opened.Cards)
+
+	testSuite.TearDownTest()
+}
+
+func TestTenant_DefaultTenantHasNoCustomCardTypes(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	// Every caller without a tenant shares the default one
+	resp, err := http.Post(testSuite.ts.URL+"/card-types", "application/json", strings.NewReader(unoCards))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	var count int64
+	testSuite.db.Model(&model.Card{}).Where("card_type = ?", "UNO").Count(&count)
+	assert.Zero(t, count)
+
+	testSuite.TearDownTest()
+}
+
+func TestTenant_GamesAreIsolated(t *testing.T) {
+	testSuite := setupTenants()
+
+	resp := tenantRequest(t, "POST", testSuite.ts.URL+"/blackjack?bet=10", "alice-key", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	game := api.BlackjackSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&game))
+
+	resp = tenantRequest(t, "POST", testSuite.ts.URL+"/table?seats=2", "alice-key", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	table := api.TableSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&table))
+
+	for _, path := range []string{"/blackjack/" + game.ID, "/table/" + table.ID} {
+		resp = tenantRequest(t, "GET", testSuite.ts.URL+path, "bob-key", "")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, path)
+		resp = tenantRequest(t, "GET", testSuite.ts.URL+path, "alice-key", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode, path)
+	}
+	for _, path := range []string{"/blackjack/" + game.ID + "/stand", "/table/" + table.ID + "/next", "/table/" + table.ID + "/deal"} {
+		resp = tenantRequest(t, "POST", testSuite.ts.URL+path, "bob-key", "")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, path)
+	}
+
+	testSuite.TearDownTest()
+}
+
+func TestTenant_Quota(t *testing.T) {
+	testSuite := setupTenants()
+	testSuite.server.TenantQuota = model.Quota{CardTypes: 1, TypeCards: 2}
+
+	resp := tenantRequest(t, "POST", testSuite.ts.URL+"/card-types", "alice-key", unoCards)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	twoCards := `{"card_type": "coin", "cards": [{"code": "HEADS", "value": "HEADS"}, {"code": "TAILS", "value": "TAILS"}]}`
+	resp = tenantRequest(t, "POST", testSuite.ts.URL+"/card-types", "alice-key", twoCards)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = tenantRequest(t, "POST", testSuite.ts.URL+"/card-types", "alice-key", strings.Replace(twoCards, "coin", "dice", 1))
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Quotas are per tenant
+	resp = tenantRequest(t, "POST", testSuite.ts.URL+"/card-types", "bob-key", twoCards)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// A tenant row overrides the default quota
+	testSuite.db.Create(&model.Tenant{ID: "studio-a", Quota: model.Quota{CardTypes: 5}})
+	resp = tenantRequest(t, "POST", testSuite.ts.URL+"/card-types", "alice-key", strings.Replace(twoCards, "coin", "dice", 1))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = tenantRequest(t, "GET", testSuite.ts.URL+"/tenant", "alice-key", "")
+	tenant := api.TenantSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&tenant))
+	assert.Equal(t, api.TenantSerializer{
+		Tenant: "studio-a",
+		Quota:  model.Quota{CardTypes: 5, TypeCards: 2},
+		Usage:  api.TenantUsageSerializer{CardTypes: 2},
+	}, tenant)
+
+	testSuite.TearDownTest()
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"toggl-test-wiliam/model"
+
+	"gorm.io/gorm"
+)
+
+type DeckTokenSerializer struct {
+	DeckID string `json:"deck_id"`
+	Role   string `json:"role"`
+	Token  string `json:"token"`
+}
+
+// MintDeckToken hands the deck owner a new token with the "role" query
+// parameter: read_only for spectators, draw for players or dealer
+func (s *Server) MintDeckToken(w http.ResponseWriter, r *http.Request) {
+	deck, ok := s.findDeck(w, r, model.RoleOwner)
+	if !ok {
+		return
+	}
+
+	role, err := model.ParseRole(r.URL.Query().Get("role"))
+	if err != nil || role == model.RoleOwner {
+		http.Error(w, "role must be read_only, draw or dealer", http.StatusBadRequest)
+		return
+	}
+
+	token, err := s.mintDeckToken(s.DB, deck.ID, role)
+	if err != nil {
+		s.Logger.Printf("mint token for deck %s: %v", deck.ID, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DeckTokenSerializer{DeckID: deck.ID, Role: role, Token: token})
+}
+
+func (s *Server) mintDeckToken(tx *gorm.DB, deckID, role string) (string, error) {
+	record, token, err := model.NewDeckToken(deckID, role)
+	if err != nil {
+		return "", err
+	}
+	return token, tx.Create(&record).Error
+}
+
+// authorizeDeck answers 401 or 403 unless the caller holds the required role
+// on the deck
+func (s *Server) authorizeDeck(w http.ResponseWriter, r *http.Request, deck model.Deck, required string) bool {
+	if err := s.authorize(callerOf(r), deck, required); err != nil {
+		fail(w, err)
+		return false
+	}
+	return true
+}
+
+// authorize fails with 401 or 403 unless the caller holds the required role
+// on the deck. Only protected decks check tokens, except for owner rights:
+// protection is opt-in on purpose, so that the deck endpoints keep working
+// without tokens for clients that never asked for them. On other decks a
+// token only matters for minting more, and one that falls short is ignored
+// rather than refused, since leaving it out would grant the same access
+func (s *Server) authorize(c caller, deck model.Deck, required string) error {
+	if !deck.Protected && required != model.RoleOwner {
+		return nil
+	}
+
+	role, err := s.deckRole(c, deck)
+	if err != nil {
+		s.Logger.Printf("load token of deck %s: %v", deck.ID, err)
+		return errDatabase
+	}
+
+	if role == "" {
+		return &deckError{status: http.StatusUnauthorized, message: "Missing or invalid deck token"}
+	}
+	if !model.Allows(role, required) {
+		return &deckError{status: http.StatusForbidden, message: "Deck token does not allow this"}
+	}
+	return nil
+}
+
+// deckRole is the role of the caller's deck token on the deck, with the
+// authenticated creator acting as its owner
+func (s *Server) deckRole(c caller, deck model.Deck) (string, error) {
+	if c.Name != "" && c.Name == deck.CreatedBy {
+		return model.RoleOwner, nil
+	}
+	if c.token == "" {
+		return "", nil
+	}
+
+	record := model.DeckToken{}
+	err := s.DB.Where("deck_id = ? AND hash = ?", deck.ID, model.HashToken(c.token)).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return record.Role, err
+}
@@ -0,0 +1,124 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	api "toggl-test-wiliam/api"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mintToken(t *testing.T, url, ownerToken, role string) api.DeckTokenSerializer {
+	resp := authRequest(t, "POST", url+"/token?role="+role, "X-Deck-Token", ownerToken)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	token := api.DeckTokenSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&token))
+	return token
+}
+
+func TestDeckToken_ProtectedDeck(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck?cards=AS,KD,QH&protected=true", "application/json", nil)
+	assert.NoError(t, err)
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+	assert.True(t, deck.Protected)
+	assert.NotEmpty(t, deck.OwnerToken)
+	url := testSuite.ts.URL + "/deck/" + deck.ID
+
+	resp, err = http.Get(url)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = http.Get(url + "?token=forged")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// A spectator link shows the deck without letting its holder draw
+	spectator := mintToken(t, url, deck.OwnerToken, "read_only")
+	assert.Equal(t, "READ_ONLY", spectator.Role)
+
+	resp, err = http.Get(url + "?token=" + spectator.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(url + "/draw?token=" + spectator.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	player := mintToken(t, url, deck.OwnerToken, "draw")
+	resp = authRequest(t, "GET", url+"/draw", "X-Deck-Token", player.Token)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = authRequest(t, "POST", url+"/sort", "X-Deck-Token", player.Token)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Only the owner hands out tokens
+	resp = authRequest(t, "POST", url+"/token?role=draw", "X-Deck-Token", player.Token)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = authRequest(t, "POST", url+"/token?role=owner", "X-Deck-Token", deck.OwnerToken)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	dealer := mintToken(t, url, deck.OwnerToken, "dealer")
+	resp = authRequest(t, "POST", url+"/undo", "X-Deck-Token", dealer.Token)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Tokens of one deck do not open another
+	resp, err = http.Post(testSuite.ts.URL+"/deck?protected=true", "application/json", nil)
+	assert.NoError(t, err)
+	other := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&other))
+
+	resp = authRequest(t, "GET", testSuite.ts.URL+"/deck/"+other.ID, "X-Deck-Token", deck.OwnerToken)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
+
+func TestDeckToken_UnprotectedDeck(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck", "application/json", nil)
+	assert.NoError(t, err)
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+	assert.False(t, deck.Protected)
+	url := testSuite.ts.URL + "/deck/" + deck.ID
+
+	resp, err = http.Get(url + "/draw")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Minting still takes the owner token
+	resp, err = http.Post(url+"/token?role=draw", "application/json", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	mintToken(t, url, deck.OwnerToken, "draw")
+
+	testSuite.TearDownTest()
+}
+
+func TestDeckToken_CreatorIsOwner(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+	testSuite.server.APIKeys = map[string]api.Principal{"alice-key": {Name: "alice"}, "bob-key": {Name: "bob"}}
+
+	resp := authRequest(t, "POST", testSuite.ts.URL+"/deck?protected=true", "X-API-Key", "alice-key")
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+	url := testSuite.ts.URL + "/deck/" + deck.ID
+
+	resp = authRequest(t, "GET", url+"/draw", "X-API-Key", "alice-key")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = authRequest(t, "GET", url, "X-API-Key", "bob-key")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"toggl-test-wiliam/model"
+
+	"gorm.io/gorm"
+)
+
+// UndoLastOperation reverts the most recent draw, shuffle or sort that has not been
+// undone yet, putting every card back where it was before, piles included
+func (s *Server) UndoLastOperation(w http.ResponseWriter, r *http.Request) {
+	deck, ok := s.findDeck(w, r, model.RoleDealer)
+	if !ok || !outsideSession(w, deck) {
+		return
+	}
+
+	events, err := model.Events(s.DB, deck.ID)
+	if err != nil {
+		s.Logger.Printf("load history of deck %s: %v", deck.ID, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	target, err := model.UndoTarget(events)
+	if errors.Is(err, model.ErrNothingToUndo) {
+		http.Error(w, "Nothing to undo", http.StatusConflict)
+		return
+	} else if err != nil {
+		s.Logger.Printf("replay deck %s: %v", deck.ID, err)
+		http.Error(w, "corrupt deck history", http.StatusInternalServerError)
+		return
+	}
+
+	undo := model.DeckEvent{DeckID: deck.ID, Type: model.EventUndone, Undoes: target}
+	restored, err := model.Replay(append(events, undo))
+	if err != nil {
+		s.Logger.Printf("replay deck %s: %v", deck.ID, err)
+		http.Error(w, "corrupt deck history", http.StatusInternalServerError)
+		return
+	}
+
+	// Cards drawn onto a pile go back from its top, and a sorted pile gets
+	// its previous order back
+	var pile *model.Pile
+	for _, event := range events {
+		if event.Seq != target || event.Pile == "" {
+			continue
+		}
+
+		loaded, ok := s.findPile(w, deck.ID, event.Pile)
+		if !ok {
+			return
+		}
+		if event.Type == model.EventSorted {
+			loaded.Cards = restored.Piles[event.Pile]
+		} else if err := loaded.Takeback(event.Cards); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		pile = &loaded
+	}
+
+	deck.Cards = s.spellOut(deck, restored.Cards)
+	deck.Shuffled = restored.Shuffled
+	deck.Remaining = restored.Remaining
+
+	err = s.transaction(s.DB, func(tx *gorm.DB) error {
+		if err := tx.Save(&deck).Error; err != nil {
+			return err
+		}
+		if pile != nil {
+			if err := tx.Save(pile).Error; err != nil {
+				return err
+			}
+		}
+		return s.recordEvents(tx, deck.ID, undo)
+	})
+	if err != nil {
+		s.Logger.Printf("save deck %s: %v", deck.ID, err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := OpenDeckSerializer{
+		ID:        deck.ID,
+		Shuffled:  deck.Shuffled,
+		Remaining: len(deck.Cards),
+		ExpiresAt: deck.ExpiresAt,
+		Cards:     deck.Cards,
+	}
+	if !deck.VisibleTo(callerID(r)) {
+		response.Cards = redact(deck.Cards)
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,76 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	api "toggl-test-wiliam/api"
+	model "toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUndoLastOperation_RevertsDraw(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck?shuffle=true&cards=AS,KS,QS,JS", "application/json", nil)
+	assert.NoError(t, err)
+
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID)
+	assert.NoError(t, err)
+
+	before := api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&before))
+
+	_, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "/draw?count=2")
+	assert.NoError(t, err)
+
+	resp, err = http.Post(testSuite.ts.URL+"/deck/"+deck.ID+"/undo", "application/json", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	undone := api.OpenDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&undone))
+	assert.Equal(t, before.Cards, undone.Cards)
+	assert.Equal(t, 4, undone.Remaining)
+
+	var stored model.Deck
+	testSuite.db.First(&stored, "id = ?", deck.ID)
+	assert.Equal(t, 4, stored.Remaining)
+	assert.Equal(t, before.Cards, stored.Cards)
+
+	resp, err = http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "/history")
+	assert.NoError(t, err)
+
+	history := api.HistorySerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&history))
+	assert.Len(t, history.Events, 4)
+	assert.Equal(t, model.EventUndone, history.Events[3].Type)
+	assert.Equal(t, 3, history.Events[3].Undoes)
+
+	testSuite.TearDownTest()
+}
+
+func TestUndoLastOperation_NothingToUndo(t *testing.T) {
+	testSuite := new(APITestSuite)
+	testSuite.SetupTest()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck", "application/json", nil)
+	assert.NoError(t, err)
+
+	deck := api.CreateDeckSerializer{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	resp, _ = http.Post(testSuite.ts.URL+"/deck/"+deck.ID+"/undo", "application/json", nil)
+	resp_body, _ := ioutil.ReadAll(resp.Body)
+
+	assert.Equal(t, "Nothing to undo\n", string(resp_body))
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
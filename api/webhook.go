@@ -0,0 +1,514 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+	"toggl-test-wiliam/model"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultWebhookRetries is how many times a failed delivery is retried
+	DefaultWebhookRetries = 5
+	// DefaultWebhookBackoff is how long before the first retry, doubling after each
+	DefaultWebhookBackoff = time.Second
+)
+
+// maxWebhookDeliveries bounds a page of the delivery log
+const maxWebhookDeliveries = 100
+
+// webhookClaim is how long a delivery being attempted is left alone before
+// another attempt may take it over, well past the client timeout
+const webhookClaim = time.Minute
+
+// errPrivateWebhook refuses webhook URLs reaching into the service's own network
+var errPrivateWebhook = errors.New("webhook url must not point at a loopback, link-local or private address")
+
+type WebhookSerializer struct {
+	model.Webhook
+	Secret string `json:"secret,omitempty"`
+}
+
+type ListWebhooksSerializer struct {
+	Webhooks []model.Webhook `json:"webhooks"`
+}
+
+type ListWebhookDeliveriesSerializer struct {
+	Deliveries []model.WebhookDelivery `json:"deliveries"`
+}
+
+// CreateWebhookRequest names the URL to call back and the events it wants,
+// all of them when left empty
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// WebhookPayload is the body of every callback; Data holds the deck log
+// entry behind created and drawn events
+type WebhookPayload struct {
+	Webhook    string           `json:"webhook_id"`
+	Event      string           `json:"event"`
+	DeckID     string           `json:"deck_id"`
+	Remaining  int              `json:"remaining"`
+	Data       *model.DeckEvent `json:"data,omitempty"`
+	OccurredAt time.Time        `json:"occurred_at"`
+}
+
+// CreateWebhook registers a webhook on every deck of the caller's tenant,
+// which takes an authenticated caller of a tenant: the default tenant is
+// shared by everyone without one
+func (s *Server) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	if principal(r) == "" || tenantOf(r) == "" {
+		http.Error(w, "Tenant webhooks need an authenticated caller of a tenant", http.StatusForbidden)
+		return
+	}
+	s.createWebhook(w, r, "")
+}
+
+// CreateDeckWebhook registers a webhook on a single deck, for its owner only
+func (s *Server) CreateDeckWebhook(w http.ResponseWriter, r *http.Request) {
+	deck, ok := s.findDeck(w, r, model.RoleOwner)
+	if !ok {
+		return
+	}
+	s.createWebhook(w, r, deck.ID)
+}
+
+func (s *Server) createWebhook(w http.ResponseWriter, r *http.Request, deckID string) {
+	req := CreateWebhookRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hook, err := model.NewWebhook(req.URL, req.Events)
+	if err == nil {
+		err = s.checkWebhookTarget(hook.URL)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	hook.Tenant = tenantOf(r)
+	hook.DeckID = deckID
+	hook.CreatedBy = principal(r)
+	hook.CreatedAt = s.Now().UTC()
+
+	if err := s.DB.Create(&hook).Error; err != nil {
+		s.Logger.Printf("create webhook: %v", err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(WebhookSerializer{Webhook: hook, Secret: hook.Secret})
+}
+
+// ListWebhooks lists the webhooks of the caller's tenant without their
+// secrets; those of a single deck are only listed for its owner, by "deck_id"
+func (s *Server) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	query := inTenant(s.DB, tenantOf(r))
+	if deckID := r.URL.Query().Get("deck_id"); deckID != "" {
+		if err := s.ownsDeck(callerOf(r), deckID); err != nil {
+			fail(w, err)
+			return
+		}
+		query = query.Where("deck_id = ?", deckID)
+	} else {
+		query = query.Where("COALESCE(deck_id, '') = ''")
+	}
+
+	webhooks := []model.Webhook{}
+	if err := query.Order("created_at").Find(&webhooks).Error; err != nil {
+		s.Logger.Printf("list webhooks: %v", err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ListWebhooksSerializer{Webhooks: webhooks})
+}
+
+// DeleteWebhook stops the callbacks of a webhook, giving up on its pending
+// deliveries and keeping its delivery log. The webhook of a single deck may
+// only be deleted by the owner of the deck, who alone could register it
+func (s *Server) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	hook := model.Webhook{}
+	inTenant(s.DB, tenantOf(r)).Limit(1).Find(&hook, "id = ?", mux.Vars(r)["webhook_id"])
+	if hook.ID == "" {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+	if hook.DeckID != "" {
+		if err := s.ownsDeck(callerOf(r), hook.DeckID); err != nil {
+			fail(w, err)
+			return
+		}
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&hook).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.WebhookDelivery{}).
+			Where("webhook_id = ? AND status = ?", hook.ID, model.DeliveryPending).
+			Updates(map[string]interface{}{"status": model.DeliveryFailed, "error": "webhook deleted", "next_attempt_at": nil}).Error
+	})
+	if err != nil {
+		s.Logger.Printf("delete webhook: %v", err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries pages through the delivery log of the caller's
+// tenant, newest first, optionally for one "webhook_id", "deck_id", "event"
+// or "status". Only the owner of a deck sees the deliveries of its own
+// webhooks, by "deck_id"; everyone else sees those of the tenant's webhooks
+func (s *Server) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	query := inTenant(s.DB, tenantOf(r))
+	if deckID := r.URL.Query().Get("deck_id"); deckID != "" {
+		if err := s.ownsDeck(callerOf(r), deckID); err != nil {
+			fail(w, err)
+			return
+		}
+	} else {
+		tenantHooks := s.DB.Unscoped().Model(&model.Webhook{}).Select("id").Where("COALESCE(deck_id, '') = ''")
+		query = query.Where("webhook_id IN (?)", tenantHooks)
+	}
+	for _, filter := range []string{"webhook_id", "deck_id", "event", "status"} {
+		if value := r.URL.Query().Get(filter); value != "" {
+			query = query.Where(filter+" = ?", value)
+		}
+	}
+
+	limit := maxWebhookDeliveries
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitParam); err != nil || limit < 1 || limit > maxWebhookDeliveries {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxWebhookDeliveries), http.StatusBadRequest)
+			return
+		}
+	}
+
+	deliveries := []model.WebhookDelivery{}
+	if err := query.Order("id DESC").Limit(limit).Find(&deliveries).Error; err != nil {
+		s.Logger.Printf("list webhook deliveries: %v", err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ListWebhookDeliveriesSerializer{Deliveries: deliveries})
+}
+
+// notifyWebhooks calls back the webhooks of the decks behind the committed
+// events: created and drawn as they are, and emptied after the draw that
+// took the last card of a deck. Callbacks leave the service, so they only
+// carry the cards of public decks and piles
+func (s *Server) notifyWebhooks(events []model.DeckEvent) {
+	var deckIDs []string
+	byDeck := map[string][]model.DeckEvent{}
+	for _, event := range events {
+		if event.Type != model.EventCreated && event.Type != model.EventDrawn {
+			continue
+		}
+		if byDeck[event.DeckID] == nil {
+			deckIDs = append(deckIDs, event.DeckID)
+		}
+		byDeck[event.DeckID] = append(byDeck[event.DeckID], event)
+	}
+
+	for _, deckID := range deckIDs {
+		deck := model.Deck{}
+		err := s.DB.Select("id", "tenant", "remaining", "owner", "visibility").Where("id = ?", deckID).Limit(1).Find(&deck).Error
+		if err != nil || deck.ID == "" {
+			continue
+		}
+		hooks, err := s.webhooksOf(s.DB, deck)
+		if err != nil {
+			s.Logger.Printf("load webhooks of deck %s: %v", deck.ID, err)
+			continue
+		}
+		if len(hooks) == 0 {
+			continue
+		}
+		hidden, err := s.hiddenPiles(deck.ID, "")
+		if err != nil {
+			s.Logger.Printf("load piles of deck %s: %v", deck.ID, err)
+			continue
+		}
+
+		emptied := false
+		for _, event := range byDeck[deckID] {
+			event := redactEvent(event, deck.VisibleTo(""), hidden)
+			s.dispatchWebhooks(hooks, deck, event.Type, &event)
+			emptied = event.Type == model.EventDrawn
+		}
+		if emptied && deck.Remaining == 0 {
+			s.dispatchWebhooks(hooks, deck, model.EventEmptied, nil)
+		}
+	}
+}
+
+// ownsDeck fails unless the caller holds owner rights on the deck of its
+// tenant, which may have expired or been purged since: its webhooks and their
+// delivery log outlive it
+func (s *Server) ownsDeck(c caller, deckID string) error {
+	deck := model.Deck{}
+	if err := notInGame(inTenant(s.DB.Unscoped(), c.Tenant)).Limit(1).Find(&deck, "id = ?", deckID).Error; err != nil {
+		s.Logger.Printf("load deck %s: %v", deckID, err)
+		return errDatabase
+	}
+	if deck.ID == "" {
+		return &deckError{status: http.StatusNotFound, message: "Deck not found"}
+	}
+	return s.authorize(c, deck, model.RoleOwner)
+}
+
+// webhooksOf lists the webhooks of the deck and of its tenant
+func (s *Server) webhooksOf(tx *gorm.DB, deck model.Deck) ([]model.Webhook, error) {
+	var hooks []model.Webhook
+	err := tx.Where("deck_id = ? OR (COALESCE(deck_id, '') = '' AND COALESCE(tenant, '') = ?)", deck.ID, deck.Tenant).
+		Order("created_at").
+		Find(&hooks).Error
+	return hooks, err
+}
+
+// dispatchWebhooks logs a delivery of the event to each webhook wanting it
+// and makes its first attempt in the background
+func (s *Server) dispatchWebhooks(hooks []model.Webhook, deck model.Deck, event string, data *model.DeckEvent) {
+	for _, hook := range hooks {
+		if !hook.Wants(event) {
+			continue
+		}
+
+		now := s.Now().UTC()
+		payload, err := json.Marshal(WebhookPayload{
+			Webhook:    hook.ID,
+			Event:      event,
+			DeckID:     deck.ID,
+			Remaining:  deck.Remaining,
+			Data:       data,
+			OccurredAt: now,
+		})
+		if err != nil {
+			s.Logger.Printf("encode %s event of deck %s: %v", event, deck.ID, err)
+			continue
+		}
+
+		// Claimed for the first attempt, and picked up by RetryWebhooks
+		// should the service stop before it is done
+		claimed := now.Add(webhookClaim)
+		delivery := model.WebhookDelivery{
+			WebhookID:     hook.ID,
+			Tenant:        deck.Tenant,
+			DeckID:        deck.ID,
+			Event:         event,
+			Payload:       payload,
+			Status:        model.DeliveryPending,
+			CreatedAt:     now,
+			NextAttemptAt: &claimed,
+		}
+		if err := s.DB.Create(&delivery).Error; err != nil {
+			s.Logger.Printf("log delivery of %s to webhook %s: %v", event, hook.ID, err)
+			continue
+		}
+
+		go s.attemptDelivery(hook, delivery)
+	}
+}
+
+// StartWebhookRetries attempts the due webhook deliveries again every
+// interval until ctx is cancelled
+func (s *Server) StartWebhookRetries(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.RetryWebhooks(); err != nil {
+					s.Logger.Printf("retry webhooks: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// RetryWebhooks attempts every pending delivery whose next attempt is due,
+// in the background, and returns how many it took on. Retries are driven by
+// the delivery log alone, so those left over by a restart go out as well
+func (s *Server) RetryWebhooks() (int, error) {
+	now := s.Now().UTC()
+	var due []model.WebhookDelivery
+	err := s.DB.Where("status = ? AND next_attempt_at <= ?", model.DeliveryPending, now).Order("id").Find(&due).Error
+	if err != nil {
+		return 0, err
+	}
+
+	taken := 0
+	for _, delivery := range due {
+		// Another attempt may have claimed the delivery in the meantime
+		claim := s.DB.Model(&model.WebhookDelivery{}).
+			Where("id = ? AND status = ? AND next_attempt_at <= ?", delivery.ID, model.DeliveryPending, now).
+			Update("next_attempt_at", now.Add(webhookClaim))
+		if claim.Error != nil {
+			return taken, claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+
+		// Webhooks of purged decks are gone, but still owed their expired event
+		hook := model.Webhook{}
+		if err := s.DB.Unscoped().Where("id = ?", delivery.WebhookID).Limit(1).Find(&hook).Error; err != nil {
+			return taken, err
+		}
+		if hook.ID == "" {
+			s.finishDelivery(delivery, map[string]interface{}{"status": model.DeliveryFailed, "error": "webhook deleted", "next_attempt_at": nil})
+			continue
+		}
+
+		go s.attemptDelivery(hook, delivery)
+		taken++
+	}
+	return taken, nil
+}
+
+// attemptDelivery posts a logged delivery once and records how it went:
+// delivered on a 2xx answer, failed once out of retries, or due again after a
+// backoff doubling with each attempt
+func (s *Server) attemptDelivery(hook model.Webhook, delivery model.WebhookDelivery) {
+	statusCode, err := s.postWebhook(hook, delivery.ID, delivery.Event, delivery.Payload)
+
+	attempts := delivery.Attempts + 1
+	now := s.Now().UTC()
+	update := map[string]interface{}{"attempts": attempts, "status_code": statusCode, "error": "", "next_attempt_at": nil}
+	switch {
+	case err == nil:
+		update["status"] = model.DeliveryDelivered
+		update["delivered_at"] = &now
+	case attempts > s.WebhookRetries:
+		update["status"] = model.DeliveryFailed
+		update["error"] = err.Error()
+	default:
+		next := now.Add(s.WebhookBackoff << (attempts - 1))
+		update["error"] = err.Error()
+		update["next_attempt_at"] = &next
+	}
+	s.finishDelivery(delivery, update)
+}
+
+// finishDelivery records an attempt on a delivery still pending, leaving
+// alone those given up on in the meantime
+func (s *Server) finishDelivery(delivery model.WebhookDelivery, update map[string]interface{}) {
+	err := s.DB.Model(&model.WebhookDelivery{}).
+		Where("id = ? AND status = ?", delivery.ID, model.DeliveryPending).
+		Updates(update).Error
+	if err != nil {
+		s.Logger.Printf("log delivery %d to webhook %s: %v", delivery.ID, delivery.WebhookID, err)
+	}
+}
+
+func (s *Server) postWebhook(hook model.Webhook, deliveryID uint, event string, payload []byte) (int, error) {
+	req, err := http.NewRequest("POST", hook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := s.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-ID", hook.ID)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(uint64(deliveryID), 10))
+	req.Header.Set("X-Webhook-Event", event)
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", model.SignWebhook(hook.Secret, timestamp, payload))
+
+	resp, err := s.WebhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// checkWebhookTarget refuses a webhook URL whose host resolves to an address
+// that is not public, so that callbacks cannot probe the service's own network
+func (s *Server) checkWebhookTarget(target string) error {
+	if s.WebhookAllowPrivate {
+		return nil
+	}
+
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(context.Background(), parsed.Hostname())
+	if err != nil {
+		return fmt.Errorf("cannot resolve webhook host %s", parsed.Hostname())
+	}
+	for _, addr := range addrs {
+		if !publicAddress(addr.IP) {
+			return errPrivateWebhook
+		}
+	}
+	return nil
+}
+
+// webhookTransport checks every address callbacks connect to as well, since
+// a host may resolve elsewhere than when its webhook was registered, and
+// redirects may lead anywhere
+func (s *Server) webhookTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if s.WebhookAllowPrivate {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicAddress(ip) {
+				return errPrivateWebhook
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would connect on the service's behalf, out of reach of the check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// publicAddress reports whether callbacks may reach ip: loopback, link-local,
+// private, unspecified and multicast addresses are kept out
+func publicAddress(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsPrivate() && !ip.IsUnspecified()
+}
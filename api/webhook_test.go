@@ -0,0 +1,408 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	api "toggl-test-wiliam/api"
+	model "toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type callback struct {
+	Event     string
+	Delivery  string
+	Timestamp int64
+	Signature string
+	Body      []byte
+	Payload   api.WebhookPayload
+}
+
+// newReceiver records every callback it gets, answering each with the next
+// of the given status codes and 200 once they run out
+func newReceiver(statuses ...int) (*httptest.Server, <-chan callback) {
+	callbacks := make(chan callback, 16)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received := callback{
+			Event:     r.Header.Get("X-Webhook-Event"),
+			Delivery:  r.Header.Get("X-Webhook-Delivery"),
+			Signature: r.Header.Get("X-Webhook-Signature"),
+			Body:      body,
+		}
+		received.Timestamp, _ = strconv.ParseInt(r.Header.Get("X-Webhook-Timestamp"), 10, 64)
+		json.Unmarshal(body, &received.Payload)
+		callbacks <- received
+
+		if len(statuses) > 0 {
+			w.WriteHeader(statuses[0])
+			statuses = statuses[1:]
+		}
+	}))
+	return receiver, callbacks
+}
+
+func nextCallback(t *testing.T, callbacks <-chan callback) callback {
+	select {
+	case received := <-callbacks:
+		return received
+	case <-time.After(5 * time.Second):
+		t.Fatal("no callback received")
+		return callback{}
+	}
+}
+
+// setupWebhooks lets webhooks call the local receivers back, for callers of
+// the tenants of setupTenants
+func setupWebhooks() *APITestSuite {
+	testSuite := setupTenants()
+	testSuite.server.WebhookBackoff = time.Millisecond
+	testSuite.server.WebhookAllowPrivate = true
+
+	// Deliveries are logged from the background, so they must share the in-memory database
+	sqlDB, _ := testSuite.db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	return testSuite
+}
+
+func registerWebhook(t *testing.T, url, body string) api.WebhookSerializer {
+	resp := tenantRequest(t, "POST", url, "alice-key", body)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	hook := api.WebhookSerializer{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&hook))
+	return hook
+}
+
+func deliveries(t *testing.T, url string) []model.WebhookDelivery {
+	resp := tenantRequest(t, "GET", url, "alice-key", "")
+	list := api.ListWebhookDeliveriesSerializer{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	return list.Deliveries
+}
+
+func TestWebhooks_TenantEvents(t *testing.T) {
+	testSuite := setupWebhooks()
+	receiver, callbacks := newReceiver(http.StatusInternalServerError)
+	defer receiver.Close()
+
+	hook := registerWebhook(t, testSuite.ts.URL+"/webhooks", `{"url": "`+receiver.URL+`"}`)
+	assert.Equal(t, model.WebhookEvents, hook.Events)
+	assert.NotEmpty(t, hook.Secret)
+
+	resp := tenantRequest(t, "POST", testSuite.ts.URL+"/deck?cards=AS,KD", "alice-key", "")
+	deck := api.CreateDeckSerializer{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+
+	// The first attempt fails and is retried with the same delivery, once due
+	failed := nextCallback(t, callbacks)
+	assert.Eventually(t, func() bool {
+		taken, err := testSuite.server.RetryWebhooks()
+		return err == nil && taken == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	created := nextCallback(t, callbacks)
+	assert.Equal(t, failed.Delivery, created.Delivery)
+	assert.Equal(t, model.EventCreated, created.Event)
+	assert.Equal(t, deck.ID, created.Payload.DeckID)
+	assert.Equal(t, 2, created.Payload.Remaining)
+	assert.Equal(t, model.EventCreated, created.Payload.Data.Type)
+	assert.Equal(t, model.SignWebhook(hook.Secret, created.Timestamp, created.Body), created.Signature)
+
+	tenantRequest(t, "GET", testSuite.ts.URL+"/deck/"+deck.ID+"/draw?count=2", "alice-key", "")
+
+	// Callbacks are sent concurrently, in no particular order
+	received := map[string]callback{}
+	for i := 0; i < 2; i++ {
+		next := nextCallback(t, callbacks)
+		received[next.Event] = next
+	}
+	require.Contains(t, received, model.EventDrawn)
+	require.Contains(t, received, model.EventEmptied)
+	assert.Equal(t, []string{"AS", "KD"}, received[model.EventDrawn].Payload.Data.Cards)
+	assert.Equal(t, 0, received[model.EventEmptied].Payload.Remaining)
+	assert.Nil(t, received[model.EventEmptied].Payload.Data)
+
+	url := testSuite.ts.URL + "/webhooks/deliveries?webhook_id=" + hook.ID
+	assert.Eventually(t, func() bool {
+		return len(deliveries(t, url+"&status=delivered")) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	log := deliveries(t, url)
+	assert.Equal(t, []string{model.EventEmptied, model.EventDrawn, model.EventCreated},
+		[]string{log[0].Event, log[1].Event, log[2].Event})
+	assert.Equal(t, 2, log[2].Attempts)
+	assert.Equal(t, http.StatusOK, log[2].StatusCode)
+	assert.NotNil(t, log[2].DeliveredAt)
+	assert.JSONEq(t, string(created.Body), string(log[2].Payload))
+
+	testSuite.TearDownTest()
+}
+
+func TestWebhooks_DeckExpiry(t *testing.T) {
+	testSuite := setupWebhooks()
+	testSuite.server.APIKeys = nil
+	receiver, callbacks := newReceiver(http.StatusBadGateway)
+	defer receiver.Close()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck?ttl=1h", "application/json", nil)
+	require.NoError(t, err)
+	deck := api.CreateDeckSerializer{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+	url := testSuite.ts.URL + "/deck/" + deck.ID + "/webhooks"
+	body := `{"url": "` + receiver.URL + `", "events": ["expired"]}`
+
+	// Only the owner of the deck may register its webhooks
+	resp, err = http.Post(url, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = http.Post(url+"?token="+deck.OwnerToken, "application/json", strings.NewReader(`{"url": "mailto:me@example.com"}`))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Post(url+"?token="+deck.OwnerToken, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	hook := api.WebhookSerializer{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&hook))
+	assert.Equal(t, deck.ID, hook.DeckID)
+
+	testSuite.server.Now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	purged, err := testSuite.server.PurgeDecks()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	expired := nextCallback(t, callbacks)
+	assert.Equal(t, model.EventExpired, expired.Event)
+	assert.Equal(t, deck.ID, expired.Payload.DeckID)
+
+	// The webhooks of a deck go with it, but still get the retries they are owed
+	list := api.ListWebhooksSerializer{}
+	resp, err = http.Get(testSuite.ts.URL + "/webhooks")
+	require.NoError(t, err)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	assert.Empty(t, list.Webhooks)
+
+	assert.Eventually(t, func() bool {
+		taken, err := testSuite.server.RetryWebhooks()
+		return err == nil && taken == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, expired.Delivery, nextCallback(t, callbacks).Delivery)
+
+	testSuite.TearDownTest()
+}
+
+func TestWebhooks_GiveUp(t *testing.T) {
+	testSuite := setupWebhooks()
+	testSuite.server.WebhookRetries = 1
+	receiver, _ := newReceiver(http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	defer receiver.Close()
+
+	hook := registerWebhook(t, testSuite.ts.URL+"/webhooks", `{"url": "`+receiver.URL+`", "events": ["created"]}`)
+	tenantRequest(t, "POST", testSuite.ts.URL+"/deck", "alice-key", "")
+
+	url := testSuite.ts.URL + "/webhooks/deliveries?status=failed"
+	assert.Eventually(t, func() bool {
+		testSuite.server.RetryWebhooks()
+		return len(deliveries(t, url)) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	failed := deliveries(t, url)[0]
+	assert.Equal(t, 2, failed.Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, failed.StatusCode)
+	assert.Equal(t, "webhook answered 503 Service Unavailable", failed.Error)
+
+	resp := tenantRequest(t, "DELETE", testSuite.ts.URL+"/webhooks/"+hook.ID, "alice-key", "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = tenantRequest(t, "DELETE", testSuite.ts.URL+"/webhooks/"+hook.ID, "alice-key", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
+
+func TestWebhooks_RetriesSurviveRestart(t *testing.T) {
+	testSuite := setupWebhooks()
+	receiver, callbacks := newReceiver()
+	defer receiver.Close()
+
+	hook := registerWebhook(t, testSuite.ts.URL+"/webhooks", `{"url": "`+receiver.URL+`"}`)
+
+	// A delivery left pending when the service stopped, due by now
+	due := time.Now().UTC().Add(-time.Second)
+	delivery := model.WebhookDelivery{
+		WebhookID:     hook.ID,
+		Tenant:        "studio-a",
+		DeckID:        "deck",
+		Event:         model.EventEmptied,
+		Payload:       []byte(`{"event": "emptied"}`),
+		Status:        model.DeliveryPending,
+		Attempts:      2,
+		NextAttemptAt: &due,
+	}
+	require.NoError(t, testSuite.db.Create(&delivery).Error)
+
+	taken, err := testSuite.server.RetryWebhooks()
+	require.NoError(t, err)
+	assert.Equal(t, 1, taken)
+	assert.Equal(t, strconv.FormatUint(uint64(delivery.ID), 10), nextCallback(t, callbacks).Delivery)
+
+	// A delivery being attempted is not taken twice
+	taken, err = testSuite.server.RetryWebhooks()
+	require.NoError(t, err)
+	assert.Zero(t, taken)
+
+	url := testSuite.ts.URL + "/webhooks/deliveries?status=delivered"
+	assert.Eventually(t, func() bool {
+		delivered := deliveries(t, url)
+		return len(delivered) == 1 && delivered[0].Attempts == 3 && delivered[0].NextAttemptAt == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	testSuite.TearDownTest()
+}
+
+func TestWebhooks_HideCards(t *testing.T) {
+	testSuite := setupWebhooks()
+	receiver, callbacks := newReceiver()
+	defer receiver.Close()
+
+	registerWebhook(t, testSuite.ts.URL+"/webhooks", `{"url": "`+receiver.URL+`", "events": ["created", "drawn"]}`)
+
+	// Cards of an owner-only deck never leave the service
+	resp := tenantRequest(t, "POST", testSuite.ts.URL+"/deck?cards=AS,KD&visibility=owner", "alice-key", "")
+	deck := api.CreateDeckSerializer{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+	created := nextCallback(t, callbacks)
+	assert.Equal(t, model.EventCreated, created.Event)
+	assert.Empty(t, created.Payload.Data.Cards)
+
+	tenantRequest(t, "GET", testSuite.ts.URL+"/deck/"+deck.ID+"/draw", "alice-key", "")
+	assert.Empty(t, nextCallback(t, callbacks).Payload.Data.Cards)
+
+	// Neither do those drawn onto a hidden pile of a public deck
+	resp = tenantRequest(t, "POST", testSuite.ts.URL+"/deck?cards=AS,KD,QH", "alice-key", "")
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+	assert.Equal(t, []string{"AS", "KD", "QH"}, nextCallback(t, callbacks).Payload.Data.Cards)
+
+	url := testSuite.ts.URL + "/deck/" + deck.ID
+	tenantRequest(t, "POST", url+"/pile?name=hand&visibility=owner", "alice-key", "")
+	tenantRequest(t, "POST", url+"/pile/hand/draw", "alice-key", "")
+	drawn := nextCallback(t, callbacks)
+	assert.Equal(t, "hand", drawn.Payload.Data.Pile)
+	assert.Empty(t, drawn.Payload.Data.Cards)
+
+	tenantRequest(t, "GET", url+"/draw", "alice-key", "")
+	assert.Equal(t, []string{"KD"}, nextCallback(t, callbacks).Payload.Data.Cards)
+
+	testSuite.TearDownTest()
+}
+
+func TestWebhooks_Registration(t *testing.T) {
+	testSuite := setupWebhooks()
+	testSuite.server.WebhookAllowPrivate = false
+	receiver, _ := newReceiver()
+	defer receiver.Close()
+
+	// Tenant webhooks take an authenticated caller of a tenant
+	testSuite.server.APIKeys["carol-key"] = api.Principal{Name: "carol"}
+	resp := tenantRequest(t, "POST", testSuite.ts.URL+"/webhooks", "carol-key", `{"url": "https://example.com/hooks"}`)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Callbacks cannot reach into the service's own network
+	for _, target := range []string{
+		receiver.URL,
+		"http://localhost/hooks",
+		"http://10.0.0.1/hooks",
+		"http://192.168.1.10/hooks",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/hooks",
+		"http://0.0.0.0/hooks",
+	} {
+		resp = tenantRequest(t, "POST", testSuite.ts.URL+"/webhooks", "alice-key", `{"url": "`+target+`"}`)
+		resp_body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, target)
+		assert.Equal(t, "webhook url must not point at a loopback, link-local or private address\n", string(resp_body), target)
+	}
+
+	// Nor through a host that resolves elsewhere once registered
+	testSuite.server.WebhookAllowPrivate = true
+	testSuite.server.WebhookRetries = 0
+	registerWebhook(t, testSuite.ts.URL+"/webhooks", `{"url": "`+receiver.URL+`", "events": ["created"]}`)
+	testSuite.server.WebhookAllowPrivate = false
+	tenantRequest(t, "POST", testSuite.ts.URL+"/deck", "alice-key", "")
+
+	url := testSuite.ts.URL + "/webhooks/deliveries?status=failed"
+	assert.Eventually(t, func() bool {
+		return len(deliveries(t, url)) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Contains(t, deliveries(t, url)[0].Error, "webhook url must not point at a loopback, link-local or private address")
+
+	testSuite.TearDownTest()
+}
+
+func TestWebhooks_DeckWebhooksOwnerOnly(t *testing.T) {
+	testSuite := setupWebhooks()
+	testSuite.server.APIKeys = nil
+	receiver, callbacks := newReceiver()
+	defer receiver.Close()
+
+	resp, err := http.Post(testSuite.ts.URL+"/deck", "application/json", nil)
+	require.NoError(t, err)
+	deck := api.CreateDeckSerializer{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
+	owner := "?token=" + deck.OwnerToken
+
+	body := `{"url": "` + receiver.URL + `", "events": ["drawn"]}`
+	resp, err = http.Post(testSuite.ts.URL+"/deck/"+deck.ID+"/webhooks"+owner, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	hook := api.WebhookSerializer{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&hook))
+
+	http.Get(testSuite.ts.URL + "/deck/" + deck.ID + "/draw")
+	nextCallback(t, callbacks)
+	assert.Eventually(t, func() bool {
+		return len(deliveries(t, testSuite.ts.URL+"/webhooks/deliveries?status=delivered&deck_id="+deck.ID+"&token="+deck.OwnerToken)) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Anyone else sharing the tenant learns nothing of the deck's webhooks
+	for _, path := range []string{"/webhooks?deck_id=" + deck.ID, "/webhooks/deliveries?deck_id=" + deck.ID} {
+		resp, err = http.Get(testSuite.ts.URL + path)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, path)
+	}
+
+	list := api.ListWebhooksSerializer{}
+	resp, err = http.Get(testSuite.ts.URL + "/webhooks")
+	require.NoError(t, err)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	assert.Empty(t, list.Webhooks)
+
+	logged := api.ListWebhookDeliveriesSerializer{}
+	resp, err = http.Get(testSuite.ts.URL + "/webhooks/deliveries?webhook_id=" + hook.ID)
+	require.NoError(t, err)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&logged))
+	assert.Empty(t, logged.Deliveries)
+
+	req, _ := http.NewRequest("DELETE", testSuite.ts.URL+"/webhooks/"+hook.ID, nil)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// The owner lists and deletes them
+	resp, err = http.Get(testSuite.ts.URL + "/webhooks" + owner + "&deck_id=" + deck.ID)
+	require.NoError(t, err)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	assert.Len(t, list.Webhooks, 1)
+
+	req, _ = http.NewRequest("DELETE", testSuite.ts.URL+"/webhooks/"+hook.ID+owner, nil)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	testSuite.TearDownTest()
+}
@@ -0,0 +1,9 @@
+// Package assets bundles the static files served next to the API
+package assets
+
+import "embed"
+
+// Cards holds one SVG per French card, named after its code, e.g. "cards/10H.svg"
+//
+//go:embed cards/*.svg
+var Cards embed.FS
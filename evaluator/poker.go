@@ -0,0 +1,206 @@
+// Package evaluator ranks hands of French cards for card games built on top of decks
+package evaluator
+
+import (
+	"fmt"
+	"sort"
+	"toggl-test-wiliam/model"
+)
+
+// Category is the kind of poker hand, ordered from weakest to strongest
+type Category int
+
+const (
+	HighCard Category = iota
+	OnePair
+	TwoPair
+	ThreeOfAKind
+	Straight
+	Flush
+	FullHouse
+	FourOfAKind
+	StraightFlush
+	RoyalFlush
+)
+
+var categoryNames = map[Category]string{
+	HighCard:      "HIGH_CARD",
+	OnePair:       "ONE_PAIR",
+	TwoPair:       "TWO_PAIR",
+	ThreeOfAKind:  "THREE_OF_A_KIND",
+	Straight:      "STRAIGHT",
+	Flush:         "FLUSH",
+	FullHouse:     "FULL_HOUSE",
+	FourOfAKind:   "FOUR_OF_A_KIND",
+	StraightFlush: "STRAIGHT_FLUSH",
+	RoyalFlush:    "ROYAL_FLUSH",
+}
+
+func (c Category) String() string {
+	return categoryNames[c]
+}
+
+// PokerHand is the best five card hand found among the evaluated cards
+type PokerHand struct {
+	Category Category
+	// Ranks break ties between hands of the same category, most significant
+	// first: the grouped ranks by group size, then the kickers
+	Ranks []int
+	Cards []string
+}
+
+var aceHigh, _ = model.LookupRules("ace_high")
+
+// EvaluatePoker ranks 5 to 7 distinct card codes by the best poker hand any
+// five of them make
+func EvaluatePoker(codes []string) (PokerHand, error) {
+	if len(codes) < 5 || len(codes) > 7 {
+		return PokerHand{}, fmt.Errorf("a poker hand needs 5 to 7 cards, got %d", len(codes))
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if !model.IsCardCode(code) {
+			return PokerHand{}, fmt.Errorf("invalid card: %s", code)
+		}
+		if seen[code] {
+			return PokerHand{}, fmt.Errorf("duplicate card: %s", code)
+		}
+		seen[code] = true
+	}
+
+	var best PokerHand
+	found := false
+	forEachFive(codes, func(five []string) {
+		hand := evaluateFive(five)
+		if !found || ComparePoker(hand, best) > 0 {
+			best = hand
+			found = true
+		}
+	})
+	return best, nil
+}
+
+// ComparePoker returns a positive number when a beats b, a negative one when
+// b beats a and 0 on a split pot
+func ComparePoker(a, b PokerHand) int {
+	if a.Category != b.Category {
+		return int(a.Category) - int(b.Category)
+	}
+	for i := range a.Ranks {
+		if i >= len(b.Ranks) {
+			break
+		}
+		if a.Ranks[i] != b.Ranks[i] {
+			return a.Ranks[i] - b.Ranks[i]
+		}
+	}
+	return 0
+}
+
+// Winners returns the indexes of the strongest hands, several on a split pot
+func Winners(hands []PokerHand) []int {
+	winners := []int{}
+	for i, hand := range hands {
+		if len(winners) == 0 {
+			winners = append(winners, i)
+			continue
+		}
+
+		switch comparison := ComparePoker(hand, hands[winners[0]]); {
+		case comparison > 0:
+			winners = []int{i}
+		case comparison == 0:
+			winners = append(winners, i)
+		}
+	}
+	return winners
+}
+
+// forEachFive calls fn with every combination of five codes
+func forEachFive(codes []string, fn func([]string)) {
+	five := make([]string, 5)
+	var pick func(start, depth int)
+	pick = func(start, depth int) {
+		if depth == 5 {
+			fn(append([]string{}, five...))
+			return
+		}
+		for i := start; i <= len(codes)-(5-depth); i++ {
+			five[depth] = codes[i]
+			pick(i+1, depth+1)
+		}
+	}
+	pick(0, 0)
+}
+
+func evaluateFive(codes []string) PokerHand {
+	cards := make([]model.Card, len(codes))
+	for i, code := range codes {
+		cards[i] = aceHigh.Annotate(model.CardFromCode(code))
+	}
+
+	// Highest ranks first, so that the hand reads the way it is compared
+	sort.SliceStable(cards, func(i, j int) bool { return cards[i].Rank > cards[j].Rank })
+
+	counts := map[int]int{}
+	flush := true
+	for _, card := range cards {
+		counts[card.Rank]++
+		if card.Suit != cards[0].Suit {
+			flush = false
+		}
+	}
+
+	// Group ranks by how often they occur, breaking ties by rank
+	groups := make([]int, 0, len(counts))
+	for rank := range counts {
+		groups = append(groups, rank)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if counts[groups[i]] != counts[groups[j]] {
+			return counts[groups[i]] > counts[groups[j]]
+		}
+		return groups[i] > groups[j]
+	})
+
+	straightHigh := 0
+	if len(groups) == 5 {
+		if cards[0].Rank-cards[4].Rank == 4 {
+			straightHigh = cards[0].Rank
+		} else if cards[0].Rank == 14 && cards[1].Rank == 5 {
+			// The wheel, A-2-3-4-5, where the ace plays low
+			straightHigh = 5
+			cards = append(cards[1:], cards[0])
+		}
+	}
+
+	hand := PokerHand{Ranks: groups, Cards: model.CardCodes(cards)}
+	switch {
+	case straightHigh == 14 && flush:
+		hand.Category = RoyalFlush
+	case straightHigh > 0 && flush:
+		hand.Category = StraightFlush
+	case counts[groups[0]] == 4:
+		hand.Category = FourOfAKind
+	case counts[groups[0]] == 3 && counts[groups[1]] == 2:
+		hand.Category = FullHouse
+	case flush:
+		hand.Category = Flush
+	case straightHigh > 0:
+		hand.Category = Straight
+	case counts[groups[0]] == 3:
+		hand.Category = ThreeOfAKind
+	case counts[groups[0]] == 2 && counts[groups[1]] == 2:
+		hand.Category = TwoPair
+	case counts[groups[0]] == 2:
+		hand.Category = OnePair
+	default:
+		hand.Category = HighCard
+	}
+
+	if straightHigh > 0 {
+		hand.Ranks = []int{straightHigh}
+	}
+	return hand
+}
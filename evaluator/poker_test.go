@@ -0,0 +1,68 @@
+package evaluator_test
+
+import (
+	"testing"
+	"toggl-test-wiliam/evaluator"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluatePoker_Categories(t *testing.T) {
+	for expected, codes := range map[evaluator.Category][]string{
+		evaluator.HighCard:      {"2C", "5D", "9H", "JS", "KC"},
+		evaluator.OnePair:       {"2C", "2D", "9H", "JS", "KC"},
+		evaluator.TwoPair:       {"2C", "2D", "9H", "9S", "KC"},
+		evaluator.ThreeOfAKind:  {"2C", "2D", "2H", "JS", "KC"},
+		evaluator.Straight:      {"5C", "6D", "7H", "8S", "9C"},
+		evaluator.Flush:         {"2H", "5H", "9H", "JH", "KH"},
+		evaluator.FullHouse:     {"2C", "2D", "2H", "KS", "KC"},
+		evaluator.FourOfAKind:   {"2C", "2D", "2H", "2S", "KC"},
+		evaluator.StraightFlush: {"5S", "6S", "7S", "8S", "9S"},
+		evaluator.RoyalFlush:    {"10D", "JD", "QD", "KD", "AD"},
+	} {
+		hand, err := evaluator.EvaluatePoker(codes)
+		require.NoError(t, err)
+		assert.Equal(t, expected, hand.Category, expected.String())
+	}
+}
+
+func TestEvaluatePoker_BestFiveOfSeven(t *testing.T) {
+	hand, err := evaluator.EvaluatePoker([]string{"AS", "KD", "2C", "3H", "4S", "5D", "KC"})
+	require.NoError(t, err)
+
+	assert.Equal(t, evaluator.Straight, hand.Category)
+	assert.Equal(t, []int{5}, hand.Ranks)
+	assert.Equal(t, []string{"5D", "4S", "3H", "2C", "AS"}, hand.Cards)
+}
+
+func TestEvaluatePoker_Kickers(t *testing.T) {
+	pairWithAce, err := evaluator.EvaluatePoker([]string{"9C", "9D", "AH", "4S", "3C"})
+	require.NoError(t, err)
+	pairWithKing, err := evaluator.EvaluatePoker([]string{"9H", "9S", "KH", "QS", "JC"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{9, 14, 4, 3}, pairWithAce.Ranks)
+	assert.Positive(t, evaluator.ComparePoker(pairWithAce, pairWithKing))
+	assert.Negative(t, evaluator.ComparePoker(pairWithKing, pairWithAce))
+}
+
+func TestWinners_SplitPot(t *testing.T) {
+	board := []string{"AS", "KS", "QD", "JC", "10H"}
+	first, _ := evaluator.EvaluatePoker(append([]string{"2C", "3D"}, board...))
+	second, _ := evaluator.EvaluatePoker(append([]string{"4C", "5D"}, board...))
+	third, _ := evaluator.EvaluatePoker([]string{"2H", "2S", "7C", "8D", "9S"})
+
+	assert.Equal(t, []int{0, 1}, evaluator.Winners([]evaluator.PokerHand{first, second, third}))
+}
+
+func TestEvaluatePoker_Invalid(t *testing.T) {
+	_, err := evaluator.EvaluatePoker([]string{"AS", "KS"})
+	assert.EqualError(t, err, "a poker hand needs 5 to 7 cards, got 2")
+
+	_, err = evaluator.EvaluatePoker([]string{"AS", "KS", "QS", "JS", "ZZ"})
+	assert.EqualError(t, err, "invalid card: ZZ")
+
+	_, err = evaluator.EvaluatePoker([]string{"AS", "KS", "QS", "JS", "AS"})
+	assert.EqualError(t, err, "duplicate card: AS")
+}
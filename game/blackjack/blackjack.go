@@ -0,0 +1,391 @@
+// Package blackjack plays rounds of blackjack against a dealer, drawing from a
+// shoe kept as a deck of the game
+package blackjack
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"toggl-test-wiliam/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	StatusPlayerTurn = "PLAYER_TURN"
+	StatusFinished   = "FINISHED"
+
+	ResultBlackjack = "BLACKJACK"
+	ResultWin       = "WIN"
+	ResultPush      = "PUSH"
+	ResultLose      = "LOSE"
+
+	// MaxHands caps how many hands a player can split into
+	MaxHands = 4
+	// MaxShoeDecks caps how many French decks a shoe is built from
+	MaxShoeDecks = 8
+
+	// Penetration is the share of a shoe dealt before its cut card comes
+	// out; the next round then starts on a freshly shuffled shoe
+	Penetration = 0.75
+)
+
+var (
+	ErrRoundOver       = errors.New("round is over")
+	ErrRoundInProgress = errors.New("round is still in progress")
+	ErrCannotDouble    = errors.New("only a hand of two cards can be doubled")
+	ErrCannotSplit     = errors.New("only a hand of two cards of the same rank can be split")
+	ErrShoeExhausted   = errors.New("not enough cards left in the shoe")
+	ErrInvalidBet      = errors.New("bet must be a positive amount")
+)
+
+var points, _ = model.LookupRules("blackjack")
+
+// Drawer takes the next card off the shoe
+type Drawer func() (model.Card, error)
+
+// Hand is one player hand, or the dealer's
+type Hand struct {
+	Cards   []string `json:"cards"`
+	Bet     int      `json:"bet,omitempty"`
+	Doubled bool     `json:"doubled,omitempty"`
+	Split   bool     `json:"split,omitempty"`
+	Done    bool     `json:"done,omitempty"`
+	Result  string   `json:"result,omitempty"`
+	Payout  int      `json:"payout,omitempty"`
+}
+
+// Game is a blackjack table for a single player, persisted between actions;
+// CreatedBy is the player who opened it, empty while authentication is off
+type Game struct {
+	ID               string    `json:"game_id" gorm:"primaryKey"`
+	DeckID           string    `json:"-"`
+	Tenant           string    `json:"-" gorm:"index"`
+	CreatedBy        string    `json:"-" gorm:"index"`
+	Decks            int       `json:"decks"`
+	DealerHitsSoft17 bool      `json:"dealer_hits_soft_17"`
+	Round            int       `json:"round"`
+	Status           string    `json:"status"`
+	Active           int       `json:"active_hand"`
+	Hands            []Hand    `json:"hands" gorm:"-"`
+	Dealer           Hand      `json:"dealer" gorm:"-"`
+	StateJSON        []byte    `json:"-" gorm:"column:state"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// TableName keeps blackjack games apart from the other games' tables
+func (Game) TableName() string {
+	return "blackjack_games"
+}
+
+type state struct {
+	Hands  []Hand `json:"hands"`
+	Dealer Hand   `json:"dealer"`
+}
+
+// NewShoe builds an unshuffled deck from the given number of copies of the
+// French card codes
+func NewShoe(codes []string, decks int) (model.Deck, error) {
+	if decks < 1 || decks > MaxShoeDecks {
+		return model.Deck{}, fmt.Errorf("a shoe holds 1 to %d decks", MaxShoeDecks)
+	}
+
+	shoe, err := (&model.Deck{}).Create(codes)
+	if err != nil {
+		return shoe, err
+	}
+
+	single := shoe.Cards
+	for i := 1; i < decks; i++ {
+		shoe.Cards = append(shoe.Cards, single...)
+	}
+	shoe.Remaining = len(shoe.Cards)
+	return shoe, nil
+}
+
+// RefillShoe builds an unshuffled shoe of the given number of decks without
+// the cards still on the table, to finish a round its shoe ran out in
+func RefillShoe(codes []string, decks int, inPlay []string) (model.Deck, error) {
+	shoe, err := NewShoe(codes, decks)
+	if err != nil {
+		return shoe, err
+	}
+
+	onTable := map[string]int{}
+	for _, code := range inPlay {
+		onTable[code]++
+	}
+	cards := []model.Card{}
+	for _, card := range shoe.Cards {
+		if onTable[card.Code] > 0 {
+			onTable[card.Code]--
+			continue
+		}
+		cards = append(cards, card)
+	}
+	shoe.Cards = cards
+	shoe.Remaining = len(cards)
+	return shoe, nil
+}
+
+// PastCutCard reports whether a shoe of size cards, with remaining left, has
+// dealt its cut card
+func PastCutCard(remaining, size int) bool {
+	return float64(remaining) <= float64(size)*(1-Penetration)
+}
+
+// NewGame opens a table on the given shoe, without dealing yet
+func NewGame(deckID string, dealerHitsSoft17 bool) *Game {
+	return &Game{
+		ID:               uuid.New().String(),
+		DeckID:           deckID,
+		DealerHitsSoft17: dealerHitsSoft17,
+		Status:           StatusFinished,
+	}
+}
+
+// Value adds up a hand, counting aces as 11 unless that busts it; soft
+// reports whether an ace is still counted as 11
+func (h Hand) Value() (total int, soft bool) {
+	aces := 0
+	for _, code := range h.Cards {
+		card := points.Annotate(model.CardFromCode(code))
+		total += *card.Points
+		if card.Rank == 14 {
+			aces++
+		}
+	}
+
+	for total > 21 && aces > 0 {
+		total -= 10
+		aces--
+	}
+	return total, aces > 0
+}
+
+// Natural reports whether the hand is a two card 21 that did not come from a split
+func (h Hand) Natural() bool {
+	total, _ := h.Value()
+	return total == 21 && len(h.Cards) == 2 && !h.Split
+}
+
+// Deal starts a new round with a single hand holding bet
+func (g *Game) Deal(bet int, draw Drawer) error {
+	if g.Status == StatusPlayerTurn {
+		return ErrRoundInProgress
+	}
+	if bet < 1 {
+		return ErrInvalidBet
+	}
+
+	g.Round++
+	g.Status = StatusPlayerTurn
+	g.Active = 0
+	g.Hands = []Hand{{Bet: bet}}
+	g.Dealer = Hand{}
+
+	// Player and dealer alternate, the dealer's second card being the hole card
+	for i := 0; i < 2; i++ {
+		if err := g.deal(&g.Hands[0], draw); err != nil {
+			return err
+		}
+		if err := g.deal(&g.Dealer, draw); err != nil {
+			return err
+		}
+	}
+
+	// The dealer peeks for blackjack, and either natural ends the round at once
+	if g.Hands[0].Natural() || g.Dealer.Natural() {
+		g.Hands[0].Done = true
+		g.settle()
+	}
+	return nil
+}
+
+// Hit adds a card to the active hand, which ends on a bust or on 21
+func (g *Game) Hit(draw Drawer) error {
+	hand, err := g.activeHand()
+	if err != nil {
+		return err
+	}
+
+	if err := g.deal(hand, draw); err != nil {
+		return err
+	}
+	if total, _ := hand.Value(); total >= 21 {
+		hand.Done = true
+	}
+	return g.advance(draw)
+}
+
+// Stand ends the active hand
+func (g *Game) Stand(draw Drawer) error {
+	hand, err := g.activeHand()
+	if err != nil {
+		return err
+	}
+
+	hand.Done = true
+	return g.advance(draw)
+}
+
+// Double doubles the bet of a two card hand, which then gets exactly one more card
+func (g *Game) Double(draw Drawer) error {
+	hand, err := g.activeHand()
+	if err != nil {
+		return err
+	}
+	if len(hand.Cards) != 2 {
+		return ErrCannotDouble
+	}
+
+	hand.Bet *= 2
+	hand.Doubled = true
+	if err := g.deal(hand, draw); err != nil {
+		return err
+	}
+	hand.Done = true
+	return g.advance(draw)
+}
+
+// Split turns a pair into two hands with the same bet, each dealt a second
+// card; split aces get only that one card
+func (g *Game) Split(draw Drawer) error {
+	hand, err := g.activeHand()
+	if err != nil {
+		return err
+	}
+	if len(hand.Cards) != 2 || len(g.Hands) >= MaxHands ||
+		points.Rank(hand.Cards[0]) != points.Rank(hand.Cards[1]) {
+		return ErrCannotSplit
+	}
+
+	aces := points.Rank(hand.Cards[0]) == 14
+	second := Hand{Cards: []string{hand.Cards[1]}, Bet: hand.Bet, Split: true}
+	hand.Cards = hand.Cards[:1]
+	hand.Split = true
+
+	// Inserting the new hand right after the active one may move the slice
+	g.Hands = append(g.Hands[:g.Active+1], append([]Hand{second}, g.Hands[g.Active+1:]...)...)
+	for _, i := range []int{g.Active, g.Active + 1} {
+		if err := g.deal(&g.Hands[i], draw); err != nil {
+			return err
+		}
+		if total, _ := g.Hands[i].Value(); aces || total == 21 {
+			g.Hands[i].Done = true
+		}
+	}
+	return g.advance(draw)
+}
+
+// InPlay lists the cards dealt in the current round, player's and dealer's
+func (g *Game) InPlay() []string {
+	var codes []string
+	for _, hand := range g.Hands {
+		codes = append(codes, hand.Cards...)
+	}
+	return append(codes, g.Dealer.Cards...)
+}
+
+func (g *Game) activeHand() (*Hand, error) {
+	if g.Status != StatusPlayerTurn {
+		return nil, ErrRoundOver
+	}
+	return &g.Hands[g.Active], nil
+}
+
+func (g *Game) deal(hand *Hand, draw Drawer) error {
+	card, err := draw()
+	if err != nil {
+		return err
+	}
+	hand.Cards = append(hand.Cards, card.Code)
+	return nil
+}
+
+// advance moves on to the next unfinished hand, letting the dealer play and
+// settling the round once every hand is done
+func (g *Game) advance(draw Drawer) error {
+	for g.Active < len(g.Hands) && g.Hands[g.Active].Done {
+		g.Active++
+	}
+	if g.Active < len(g.Hands) {
+		return nil
+	}
+	g.Active = len(g.Hands) - 1
+
+	// The dealer only draws when some hand is still standing
+	for _, hand := range g.Hands {
+		if total, _ := hand.Value(); total <= 21 {
+			if err := g.playDealer(draw); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	g.settle()
+	return nil
+}
+
+// playDealer hits below 17, and on a soft 17 when the table plays H17
+func (g *Game) playDealer(draw Drawer) error {
+	for {
+		total, soft := g.Dealer.Value()
+		if total > 17 || (total == 17 && !(soft && g.DealerHitsSoft17)) {
+			return nil
+		}
+		if err := g.deal(&g.Dealer, draw); err != nil {
+			return err
+		}
+	}
+}
+
+// settle decides every hand against the dealer; a blackjack pays 3:2
+func (g *Game) settle() {
+	dealer, _ := g.Dealer.Value()
+	for i := range g.Hands {
+		hand := &g.Hands[i]
+		total, _ := hand.Value()
+
+		switch {
+		case hand.Natural() && g.Dealer.Natural():
+			hand.Result, hand.Payout = ResultPush, 0
+		case hand.Natural():
+			hand.Result, hand.Payout = ResultBlackjack, hand.Bet*3/2
+		case total > 21, g.Dealer.Natural():
+			hand.Result, hand.Payout = ResultLose, -hand.Bet
+		case dealer > 21, total > dealer:
+			hand.Result, hand.Payout = ResultWin, hand.Bet
+		case total == dealer:
+			hand.Result, hand.Payout = ResultPush, 0
+		default:
+			hand.Result, hand.Payout = ResultLose, -hand.Bet
+		}
+	}
+	g.Status = StatusFinished
+}
+
+// Implement BeforeSave hook to encode the hands to JSON
+func (g *Game) BeforeSave(*gorm.DB) error {
+	var err error
+	g.StateJSON, err = json.Marshal(state{Hands: g.Hands, Dealer: g.Dealer})
+	return err
+}
+
+// Implement AfterFind hook to decode the hands from JSON
+func (g *Game) AfterFind(*gorm.DB) error {
+	if len(g.StateJSON) == 0 {
+		return nil
+	}
+
+	saved := state{}
+	if err := json.Unmarshal(g.StateJSON, &saved); err != nil {
+		return err
+	}
+	g.Hands, g.Dealer = saved.Hands, saved.Dealer
+	return nil
+}
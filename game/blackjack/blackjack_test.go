@@ -0,0 +1,137 @@
+package blackjack_test
+
+import (
+	"testing"
+	"toggl-test-wiliam/game/blackjack"
+	"toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stack deals the given codes in order
+func stack(codes ...string) blackjack.Drawer {
+	return func() (model.Card, error) {
+		if len(codes) == 0 {
+			return model.Card{}, blackjack.ErrShoeExhausted
+		}
+		card := model.CardFromCode(codes[0])
+		codes = codes[1:]
+		return card, nil
+	}
+}
+
+func TestHand_Value(t *testing.T) {
+	total, soft := blackjack.Hand{Cards: []string{"AS", "6H"}}.Value()
+	assert.Equal(t, 17, total)
+	assert.True(t, soft)
+
+	total, soft = blackjack.Hand{Cards: []string{"AS", "6H", "KD"}}.Value()
+	assert.Equal(t, 17, total)
+	assert.False(t, soft)
+
+	total, _ = blackjack.Hand{Cards: []string{"AS", "AH", "9D"}}.Value()
+	assert.Equal(t, 21, total)
+}
+
+func TestGame_HitAndStand(t *testing.T) {
+	game := blackjack.NewGame("shoe", false)
+	// Player 10-6 hits a 4 to stand on 20, dealer 9-7 draws a 5 to 21
+	draw := stack("10S", "9H", "6C", "7D", "4S", "5C")
+
+	require.NoError(t, game.Deal(10, draw))
+	assert.Equal(t, blackjack.StatusPlayerTurn, game.Status)
+
+	require.NoError(t, game.Hit(draw))
+	assert.Equal(t, []string{"10S", "6C", "4S"}, game.Hands[0].Cards)
+
+	require.NoError(t, game.Stand(draw))
+	assert.Equal(t, blackjack.StatusFinished, game.Status)
+	assert.Equal(t, []string{"9H", "7D", "5C"}, game.Dealer.Cards)
+	assert.Equal(t, blackjack.ResultLose, game.Hands[0].Result)
+	assert.Equal(t, -10, game.Hands[0].Payout)
+
+	assert.ErrorIs(t, game.Hit(draw), blackjack.ErrRoundOver)
+}
+
+func TestGame_NaturalPaysThreeToTwo(t *testing.T) {
+	game := blackjack.NewGame("shoe", false)
+
+	require.NoError(t, game.Deal(10, stack("AS", "9H", "KC", "7D")))
+	assert.Equal(t, blackjack.StatusFinished, game.Status)
+	assert.Equal(t, blackjack.ResultBlackjack, game.Hands[0].Result)
+	assert.Equal(t, 15, game.Hands[0].Payout)
+}
+
+func TestGame_DealerSoft17(t *testing.T) {
+	for hitsSoft17, expected := range map[bool][]string{
+		false: {"AH", "6D"},
+		true:  {"AH", "6D", "2C"},
+	} {
+		game := blackjack.NewGame("shoe", hitsSoft17)
+		draw := stack("10S", "AH", "8C", "6D", "2C")
+
+		require.NoError(t, game.Deal(10, draw))
+		require.NoError(t, game.Stand(draw))
+		assert.Equal(t, expected, game.Dealer.Cards)
+	}
+}
+
+func TestGame_DoubleAndSplit(t *testing.T) {
+	game := blackjack.NewGame("shoe", false)
+	draw := stack("8S", "10H", "8C", "7D", "3S", "KH", "10C")
+
+	require.NoError(t, game.Deal(10, draw))
+	require.NoError(t, game.Split(draw))
+	assert.Len(t, game.Hands, 2)
+	assert.Equal(t, []string{"8S", "3S"}, game.Hands[0].Cards)
+	assert.Equal(t, []string{"8C", "KH"}, game.Hands[1].Cards)
+
+	assert.ErrorIs(t, game.Split(draw), blackjack.ErrCannotSplit)
+	require.NoError(t, game.Double(draw))
+	assert.Equal(t, 20, game.Hands[0].Bet)
+	assert.Equal(t, 1, game.Active)
+
+	require.NoError(t, game.Stand(draw))
+	assert.Equal(t, blackjack.StatusFinished, game.Status)
+	assert.Equal(t, []string{"10H", "7D"}, game.Dealer.Cards)
+
+	// 8-3-10 beats 17 for twice the bet, 8-K beats it too
+	assert.Equal(t, blackjack.ResultWin, game.Hands[0].Result)
+	assert.Equal(t, 20, game.Hands[0].Payout)
+	assert.Equal(t, blackjack.ResultWin, game.Hands[1].Result)
+	assert.Equal(t, 10, game.Hands[1].Payout)
+}
+
+func TestNewShoe(t *testing.T) {
+	codes := []string{"AS", "KS", "QS", "JS"}
+
+	shoe, err := blackjack.NewShoe(codes, 3)
+	require.NoError(t, err)
+	assert.Equal(t, 12, shoe.Remaining)
+	assert.Equal(t, append(append(codes, codes...), codes...), model.CardCodes(shoe.Cards))
+
+	_, err = blackjack.NewShoe(codes, 9)
+	assert.EqualError(t, err, "a shoe holds 1 to 8 decks")
+}
+
+func TestRefillShoe(t *testing.T) {
+	codes := []string{"AS", "KS", "QS", "JS"}
+
+	shoe, err := blackjack.RefillShoe(codes, 2, []string{"AS", "QS", "AS"})
+	require.NoError(t, err)
+	assert.Equal(t, 5, shoe.Remaining)
+	assert.Equal(t, []string{"KS", "JS", "KS", "QS", "JS"}, model.CardCodes(shoe.Cards))
+}
+
+func TestPastCutCard(t *testing.T) {
+	assert.False(t, blackjack.PastCutCard(14, 52))
+	assert.True(t, blackjack.PastCutCard(13, 52))
+	assert.True(t, blackjack.PastCutCard(0, 52))
+}
+
+func TestGame_InPlay(t *testing.T) {
+	game := blackjack.NewGame("shoe", false)
+	require.NoError(t, game.Deal(10, stack("10S", "9H", "6C", "7D")))
+	assert.Equal(t, []string{"10S", "6C", "9H", "7D"}, game.InPlay())
+}
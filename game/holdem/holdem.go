@@ -0,0 +1,268 @@
+// Package holdem deals hands of Texas Hold'em around a table, using a fresh
+// deck for every hand and settling the showdown with the poker evaluator
+package holdem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"toggl-test-wiliam/evaluator"
+	"toggl-test-wiliam/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	StreetPreflop  = "PREFLOP"
+	StreetFlop     = "FLOP"
+	StreetTurn     = "TURN"
+	StreetRiver    = "RIVER"
+	StreetShowdown = "SHOWDOWN"
+
+	MinSeats = 2
+	// MaxSeats keeps hole cards, burns and board within a single deck
+	MaxSeats = 10
+)
+
+var (
+	ErrHandOver       = errors.New("hand is over")
+	ErrHandInProgress = errors.New("hand is still in progress")
+	ErrNoSuchSeat     = errors.New("no such seat at the table")
+	ErrSeatTaken      = errors.New("seat is already taken")
+	ErrAlreadySeated  = errors.New("player already sits at the table")
+	ErrFewPlayers     = errors.New("a hand needs at least two seated players")
+)
+
+// Drawer takes the next card off the hand's deck
+type Drawer func() (model.Card, error)
+
+// Seat is one player at the table, empty until someone sits down; only seats
+// taken when a hand is dealt get cards, and the hand is only set at showdown
+type Seat struct {
+	Player string   `json:"player,omitempty"`
+	Cards  []string `json:"cards"`
+	Hand   string   `json:"hand,omitempty"`
+	Ranks  []int    `json:"ranks,omitempty"`
+	Best   []string `json:"best,omitempty"`
+}
+
+// Table is a Hold'em table, persisted between streets. The deck of the
+// current hand and the burned cards are never serialized, so players cannot
+// learn the order of the cards still to come
+type Table struct {
+	ID        string    `json:"table_id" gorm:"primaryKey"`
+	DeckID    string    `json:"-"`
+	Tenant    string    `json:"-" gorm:"index"`
+	HandNo    int       `json:"hand_no"`
+	Street    string    `json:"street"`
+	Button    int       `json:"button"`
+	Seats     []Seat    `json:"seats" gorm:"-"`
+	Board     []string  `json:"board" gorm:"-"`
+	Burned    []string  `json:"-" gorm:"-"`
+	Winners   []int     `json:"winners,omitempty" gorm:"-"`
+	StateJSON []byte    `json:"-" gorm:"column:state"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type state struct {
+	Seats   []Seat   `json:"seats"`
+	Board   []string `json:"board"`
+	Burned  []string `json:"burned"`
+	Winners []int    `json:"winners"`
+}
+
+// TableName keeps Hold'em tables apart from the other games' tables
+func (Table) TableName() string {
+	return "holdem_tables"
+}
+
+// NewTable sets up the given number of empty seats, without dealing yet
+func NewTable(seats int) (*Table, error) {
+	if seats < MinSeats || seats > MaxSeats {
+		return nil, fmt.Errorf("a table seats %d to %d players", MinSeats, MaxSeats)
+	}
+
+	return &Table{
+		ID:     uuid.New().String(),
+		Street: StreetShowdown,
+		Button: seats - 1,
+		Seats:  make([]Seat, seats),
+		Board:  []string{},
+	}, nil
+}
+
+// Deal moves the button to the next taken seat and starts a new hand on the
+// given deck, two hole cards per taken seat dealt one at a time from the left
+// of the button
+func (t *Table) Deal(deckID string, draw Drawer) error {
+	if t.Street != StreetShowdown {
+		return ErrHandInProgress
+	}
+	if t.players() < MinSeats {
+		return ErrFewPlayers
+	}
+
+	t.HandNo++
+	t.DeckID = deckID
+	t.Street = StreetPreflop
+	t.Button = t.nextPlayer(t.Button)
+	t.Board = []string{}
+	t.Burned = []string{}
+	t.Winners = nil
+	for i := range t.Seats {
+		t.Seats[i] = Seat{Player: t.Seats[i].Player, Cards: []string{}}
+	}
+
+	for round := 0; round < 2; round++ {
+		seat := t.Button
+		for i := 0; i < t.players(); i++ {
+			seat = t.nextPlayer(seat)
+			card, err := draw()
+			if err != nil {
+				return err
+			}
+			t.Seats[seat].Cards = append(t.Seats[seat].Cards, card.Code)
+		}
+	}
+	return nil
+}
+
+// players counts the taken seats
+func (t *Table) players() int {
+	count := 0
+	for _, seat := range t.Seats {
+		if seat.Player != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// nextPlayer is the first taken seat left of the given one
+func (t *Table) nextPlayer(seat int) int {
+	for i := 1; i <= len(t.Seats); i++ {
+		next := (seat + i) % len(t.Seats)
+		if t.Seats[next].Player != "" {
+			return next
+		}
+	}
+	return seat
+}
+
+// Sit binds a free seat to the player, who may only hold one seat
+func (t *Table) Sit(seat int, player string) error {
+	if seat < 0 || seat >= len(t.Seats) {
+		return ErrNoSuchSeat
+	}
+	if t.SeatOf(player) >= 0 {
+		return ErrAlreadySeated
+	}
+	if t.Seats[seat].Player != "" {
+		return ErrSeatTaken
+	}
+
+	t.Seats[seat].Player = player
+	return nil
+}
+
+// SeatOf finds the seat of the player, -1 when they do not sit at the table
+func (t *Table) SeatOf(player string) int {
+	if player == "" {
+		return -1
+	}
+	for i, seat := range t.Seats {
+		if seat.Player == player {
+			return i
+		}
+	}
+	return -1
+}
+
+// Next deals the following street, burning a card before each, and settles
+// the showdown after the river
+func (t *Table) Next(draw Drawer) error {
+	switch t.Street {
+	case StreetPreflop:
+		t.Street = StreetFlop
+		return t.burnAndTurn(3, draw)
+	case StreetFlop:
+		t.Street = StreetTurn
+		return t.burnAndTurn(1, draw)
+	case StreetTurn:
+		t.Street = StreetRiver
+		return t.burnAndTurn(1, draw)
+	case StreetRiver:
+		return t.showdown()
+	default:
+		return ErrHandOver
+	}
+}
+
+func (t *Table) burnAndTurn(count int, draw Drawer) error {
+	burned, err := draw()
+	if err != nil {
+		return err
+	}
+	t.Burned = append(t.Burned, burned.Code)
+
+	for i := 0; i < count; i++ {
+		card, err := draw()
+		if err != nil {
+			return err
+		}
+		t.Board = append(t.Board, card.Code)
+	}
+	return nil
+}
+
+// showdown evaluates the hole cards of every seat dealt into the hand with
+// the board and keeps the winning seats, several on a split pot
+func (t *Table) showdown() error {
+	hands := []evaluator.PokerHand{}
+	dealt := []int{}
+	for i := range t.Seats {
+		seat := &t.Seats[i]
+		if len(seat.Cards) == 0 {
+			continue
+		}
+		hand, err := evaluator.EvaluatePoker(append(append([]string{}, seat.Cards...), t.Board...))
+		if err != nil {
+			return err
+		}
+
+		seat.Hand, seat.Ranks, seat.Best = hand.Category.String(), hand.Ranks, hand.Cards
+		hands = append(hands, hand)
+		dealt = append(dealt, i)
+	}
+
+	t.Winners = nil
+	for _, winner := range evaluator.Winners(hands) {
+		t.Winners = append(t.Winners, dealt[winner])
+	}
+	t.Street = StreetShowdown
+	return nil
+}
+
+// Implement BeforeSave hook to encode the seats and board to JSON
+func (t *Table) BeforeSave(*gorm.DB) error {
+	var err error
+	t.StateJSON, err = json.Marshal(state{Seats: t.Seats, Board: t.Board, Burned: t.Burned, Winners: t.Winners})
+	return err
+}
+
+// Implement AfterFind hook to decode the seats and board from JSON
+func (t *Table) AfterFind(*gorm.DB) error {
+	if len(t.StateJSON) == 0 {
+		return nil
+	}
+
+	saved := state{}
+	if err := json.Unmarshal(t.StateJSON, &saved); err != nil {
+		return err
+	}
+	t.Seats, t.Board, t.Burned, t.Winners = saved.Seats, saved.Board, saved.Burned, saved.Winners
+	return nil
+}
@@ -0,0 +1,152 @@
+package holdem_test
+
+import (
+	"errors"
+	"testing"
+	"toggl-test-wiliam/game/holdem"
+	"toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stack deals the given codes in order
+func stack(codes ...string) holdem.Drawer {
+	return func() (model.Card, error) {
+		if len(codes) == 0 {
+			return model.Card{}, errors.New("stack is empty")
+		}
+		card := model.CardFromCode(codes[0])
+		codes = codes[1:]
+		return card, nil
+	}
+}
+
+func TestNewTable_SeatLimits(t *testing.T) {
+	_, err := holdem.NewTable(1)
+	assert.Error(t, err)
+	_, err = holdem.NewTable(holdem.MaxSeats + 1)
+	assert.Error(t, err)
+
+	table, err := holdem.NewTable(3)
+	require.NoError(t, err)
+	assert.Len(t, table.Seats, 3)
+	assert.Equal(t, holdem.StreetShowdown, table.Street)
+}
+
+func TestTable_Sit(t *testing.T) {
+	table, err := holdem.NewTable(2)
+	require.NoError(t, err)
+
+	assert.Equal(t, -1, table.SeatOf("alice"))
+	require.NoError(t, table.Sit(1, "alice"))
+	assert.Equal(t, 1, table.SeatOf("alice"))
+	assert.ErrorIs(t, table.Sit(0, "alice"), holdem.ErrAlreadySeated)
+	assert.ErrorIs(t, table.Sit(1, "bob"), holdem.ErrSeatTaken)
+	assert.ErrorIs(t, table.Sit(2, "bob"), holdem.ErrNoSuchSeat)
+	assert.Equal(t, -1, table.SeatOf(""))
+
+	// Players keep their seats from one hand to the next
+	require.NoError(t, table.Sit(0, "bob"))
+	require.NoError(t, table.Deal("deck", stack("KH", "AS", "KD", "AH")))
+	assert.Equal(t, 1, table.SeatOf("alice"))
+}
+
+// seated opens a table with a player at each of its seats
+func seated(t *testing.T, seats int) *holdem.Table {
+	table, err := holdem.NewTable(seats)
+	require.NoError(t, err)
+	for i := 0; i < seats; i++ {
+		require.NoError(t, table.Sit(i, string(rune('a'+i))))
+	}
+	return table
+}
+
+func TestTable_PlayHand(t *testing.T) {
+	table := seated(t, 2)
+
+	draw := stack(
+		// Hole cards, starting left of the button at seat 0
+		"KH", "AS", "KD", "AH",
+		// Burn and flop, burn and turn, burn and river
+		"2C", "KS", "7D", "3H",
+		"4C", "9S",
+		"5C", "JD",
+	)
+
+	require.NoError(t, table.Deal("deck", draw))
+	assert.Equal(t, 1, table.HandNo)
+	assert.Equal(t, 0, table.Button)
+	assert.Equal(t, holdem.StreetPreflop, table.Street)
+	assert.Equal(t, []string{"AS", "AH"}, table.Seats[0].Cards)
+	assert.Equal(t, []string{"KH", "KD"}, table.Seats[1].Cards)
+	assert.ErrorIs(t, table.Deal("deck", draw), holdem.ErrHandInProgress)
+
+	require.NoError(t, table.Next(draw))
+	assert.Equal(t, holdem.StreetFlop, table.Street)
+	assert.Equal(t, []string{"KS", "7D", "3H"}, table.Board)
+
+	require.NoError(t, table.Next(draw))
+	require.NoError(t, table.Next(draw))
+	assert.Equal(t, holdem.StreetRiver, table.Street)
+	assert.Equal(t, []string{"KS", "7D", "3H", "9S", "JD"}, table.Board)
+	assert.Equal(t, []string{"2C", "4C", "5C"}, table.Burned)
+
+	require.NoError(t, table.Next(draw))
+	assert.Equal(t, holdem.StreetShowdown, table.Street)
+	assert.Equal(t, "ONE_PAIR", table.Seats[0].Hand)
+	assert.Equal(t, "THREE_OF_A_KIND", table.Seats[1].Hand)
+	assert.Equal(t, []int{1}, table.Winners)
+
+	assert.ErrorIs(t, table.Next(draw), holdem.ErrHandOver)
+}
+
+func TestTable_SplitPot(t *testing.T) {
+	table := seated(t, 2)
+
+	// The board plays a broadway straight for both seats
+	draw := stack(
+		"2C", "2D", "3C", "3D",
+		"4H", "10S", "JD", "QH",
+		"5H", "KC",
+		"6H", "AS",
+	)
+
+	require.NoError(t, table.Deal("deck", draw))
+	for table.Street != holdem.StreetShowdown {
+		require.NoError(t, table.Next(draw))
+	}
+	assert.Equal(t, "STRAIGHT", table.Seats[0].Hand)
+	assert.Equal(t, []int{0, 1}, table.Winners)
+}
+
+func TestTable_EmptySeatsSitOut(t *testing.T) {
+	table, err := holdem.NewTable(3)
+	require.NoError(t, err)
+	require.NoError(t, table.Sit(0, "alice"))
+	assert.ErrorIs(t, table.Deal("deck", stack()), holdem.ErrFewPlayers)
+	require.NoError(t, table.Sit(2, "bob"))
+
+	// The empty seat 1 is neither dealt nor evaluated
+	draw := stack(
+		"2C", "7D", "3C", "8D",
+		"4H", "KS", "QH", "9S",
+		"5H", "4D",
+		"6H", "JC",
+	)
+	require.NoError(t, table.Deal("deck", draw))
+	assert.Equal(t, 0, table.Button)
+	assert.Equal(t, []string{"7D", "8D"}, table.Seats[0].Cards)
+	assert.Empty(t, table.Seats[1].Cards)
+	assert.Equal(t, []string{"2C", "3C"}, table.Seats[2].Cards)
+
+	for table.Street != holdem.StreetShowdown {
+		require.NoError(t, table.Next(draw))
+	}
+	assert.Empty(t, table.Seats[1].Hand)
+	assert.Equal(t, []int{0}, table.Winners)
+
+	// The button skips the empty seat too
+	require.NoError(t, table.Deal("deck", stack("2C", "3C", "4C", "5C")))
+	assert.Equal(t, 2, table.Button)
+}
@@ -0,0 +1,352 @@
+// Package solitaire plays Klondike, dealing the layout from a shuffled French
+// deck and checking every move against the rules
+package solitaire
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"toggl-test-wiliam/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	StatusPlaying = "PLAYING"
+	StatusWon     = "WON"
+
+	PileWaste      = "waste"
+	PileFoundation = "foundation"
+	PileTableau    = "tableau"
+
+	// DeckSize is the number of cards dealt into the layout
+	DeckSize = 52
+	// Columns is the number of tableau piles
+	Columns = 7
+)
+
+var (
+	ErrGameWon      = errors.New("game is already won")
+	ErrStockEmpty   = errors.New("stock and waste are both empty")
+	ErrIllegalMove  = errors.New("illegal move")
+	ErrUnknownPile  = errors.New("unknown pile")
+	ErrDeckTooSmall = errors.New("not enough cards to deal the layout")
+)
+
+// Suits lists the foundations in order
+var Suits = []string{"C", "D", "H", "S"}
+
+var aceLow, _ = model.LookupRules("ace_low")
+
+// Drawer takes the next card off the shuffled deck
+type Drawer func() (model.Card, error)
+
+// Column is a tableau pile; its first Hidden cards lie face down
+type Column struct {
+	Cards  []string `json:"cards"`
+	Hidden int      `json:"hidden"`
+}
+
+// Foundation is built up from the ace to the king of a single suit
+type Foundation struct {
+	Suit  string   `json:"suit"`
+	Cards []string `json:"cards"`
+}
+
+// Game is a game of Klondike, persisted between moves
+type Game struct {
+	ID          string       `json:"game_id" gorm:"primaryKey"`
+	DeckID      string       `json:"-"`
+	Tenant      string       `json:"-" gorm:"index"`
+	DrawCount   int          `json:"draw_count"`
+	Status      string       `json:"status"`
+	Moves       int          `json:"moves"`
+	Passes      int          `json:"passes"`
+	Tableau     []Column     `json:"tableau" gorm:"-"`
+	Foundations []Foundation `json:"foundations" gorm:"-"`
+	Stock       []string     `json:"-" gorm:"-"`
+	Waste       []string     `json:"waste" gorm:"-"`
+	StateJSON   []byte       `json:"-" gorm:"column:state"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+type state struct {
+	Tableau     []Column     `json:"tableau"`
+	Foundations []Foundation `json:"foundations"`
+	Stock       []string     `json:"stock"`
+	Waste       []string     `json:"waste"`
+}
+
+// TableName keeps solitaire games apart from the other games' tables
+func (Game) TableName() string {
+	return "solitaire_games"
+}
+
+// NewGame starts a game turning drawCount cards, 1 or 3, from the stock at a time
+func NewGame(deckID string, drawCount int) (*Game, error) {
+	if drawCount != 1 && drawCount != 3 {
+		return nil, errors.New("draw count must be 1 or 3")
+	}
+
+	game := &Game{
+		ID:          uuid.New().String(),
+		DeckID:      deckID,
+		DrawCount:   drawCount,
+		Status:      StatusPlaying,
+		Tableau:     make([]Column, Columns),
+		Foundations: []Foundation{},
+		Stock:       []string{},
+		Waste:       []string{},
+	}
+	for i := range game.Tableau {
+		game.Tableau[i].Cards = []string{}
+	}
+	for _, suit := range Suits {
+		game.Foundations = append(game.Foundations, Foundation{Suit: suit, Cards: []string{}})
+	}
+	return game, nil
+}
+
+// Deal lays out the tableau row by row, the n-th column getting n cards of
+// which only the last is face up, and leaves the rest in the stock
+func (g *Game) Deal(draw Drawer) error {
+	for row := 0; row < Columns; row++ {
+		for col := row; col < Columns; col++ {
+			card, err := draw()
+			if err != nil {
+				return ErrDeckTooSmall
+			}
+			g.Tableau[col].Cards = append(g.Tableau[col].Cards, card.Code)
+		}
+	}
+	for col := range g.Tableau {
+		g.Tableau[col].Hidden = col
+	}
+
+	for len(g.Stock) < DeckSize-Columns*(Columns+1)/2 {
+		card, err := draw()
+		if err != nil {
+			return ErrDeckTooSmall
+		}
+		g.Stock = append(g.Stock, card.Code)
+	}
+	return nil
+}
+
+// Draw turns the next cards of the stock onto the waste, or turns the waste
+// back over into the stock once the stock runs out
+func (g *Game) Draw() error {
+	if g.Status == StatusWon {
+		return ErrGameWon
+	}
+
+	if len(g.Stock) == 0 {
+		if len(g.Waste) == 0 {
+			return ErrStockEmpty
+		}
+		for i := len(g.Waste) - 1; i >= 0; i-- {
+			g.Stock = append(g.Stock, g.Waste[i])
+		}
+		g.Waste = []string{}
+		g.Passes++
+		g.Moves++
+		return nil
+	}
+
+	for i := 0; i < g.DrawCount && len(g.Stock) > 0; i++ {
+		top := len(g.Stock) - 1
+		g.Waste = append(g.Waste, g.Stock[top])
+		g.Stock = g.Stock[:top]
+	}
+	g.Moves++
+	return nil
+}
+
+// Move takes count cards off the top of one pile onto another. Piles are
+// named "waste", "foundation:<suit>" and "tableau:<0-6>"; "foundation" alone
+// as a destination picks the foundation of the moved card's suit
+func (g *Game) Move(from, to string, count int) error {
+	if g.Status == StatusWon {
+		return ErrGameWon
+	}
+	if count < 1 {
+		return fmt.Errorf("%w: at least one card must be moved", ErrIllegalMove)
+	}
+
+	source, err := g.pile(from, "")
+	if err != nil {
+		return err
+	}
+	cards := *source
+	if count > len(cards) {
+		return fmt.Errorf("%w: %s holds %d cards", ErrIllegalMove, from, len(cards))
+	}
+	moved := cards[len(cards)-count:]
+
+	// Only face up runs leave the tableau, and only single cards leave other piles
+	if column := g.column(from); column != nil {
+		if len(cards)-count < column.Hidden {
+			return fmt.Errorf("%w: cannot move face down cards", ErrIllegalMove)
+		}
+	} else if count > 1 {
+		return fmt.Errorf("%w: only one card can leave %s", ErrIllegalMove, from)
+	}
+
+	target, err := g.pile(to, suitOf(moved[0]))
+	if err != nil {
+		return err
+	}
+	if target == source {
+		return fmt.Errorf("%w: source and destination are the same pile", ErrIllegalMove)
+	}
+
+	if _, suit, _ := strings.Cut(to, ":"); strings.HasPrefix(to, PileFoundation) {
+		if suit == "" {
+			suit = suitOf(moved[0])
+		}
+		err = canBuildFoundation(suit, *target, moved)
+	} else if strings.HasPrefix(to, PileTableau) {
+		err = canBuildTableau(*target, moved[0])
+	} else {
+		err = fmt.Errorf("%w: cannot move onto %s", ErrIllegalMove, to)
+	}
+	if err != nil {
+		return err
+	}
+
+	*target = append(*target, moved...)
+	*source = cards[:len(cards)-count]
+
+	// The card uncovered in the tableau is turned face up
+	if column := g.column(from); column != nil && column.Hidden > 0 && column.Hidden == len(column.Cards) {
+		column.Hidden--
+	}
+
+	g.Moves++
+	g.checkWon()
+	return nil
+}
+
+// pile resolves a pile name; suit picks the foundation when none is named
+func (g *Game) pile(name, suit string) (*[]string, error) {
+	kind, index, _ := strings.Cut(name, ":")
+	switch kind {
+	case PileWaste:
+		if index == "" {
+			return &g.Waste, nil
+		}
+	case PileFoundation:
+		if index == "" {
+			index = suit
+		}
+		for i := range g.Foundations {
+			if g.Foundations[i].Suit == index {
+				return &g.Foundations[i].Cards, nil
+			}
+		}
+	case PileTableau:
+		if column := g.column(name); column != nil {
+			return &column.Cards, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownPile, name)
+}
+
+// column returns the tableau column for a "tableau:<n>" name, nil otherwise
+func (g *Game) column(name string) *Column {
+	kind, index, _ := strings.Cut(name, ":")
+	if kind != PileTableau {
+		return nil
+	}
+
+	col, err := strconv.Atoi(index)
+	if err != nil || col < 0 || col >= len(g.Tableau) {
+		return nil
+	}
+	return &g.Tableau[col]
+}
+
+func (g *Game) checkWon() {
+	for _, foundation := range g.Foundations {
+		if len(foundation.Cards) != 13 {
+			return
+		}
+	}
+	g.Status = StatusWon
+}
+
+// canBuildFoundation accepts a single card of the foundation's suit, an ace
+// on an empty foundation and otherwise the next rank up
+func canBuildFoundation(suit string, foundation, moved []string) error {
+	if len(moved) != 1 {
+		return fmt.Errorf("%w: foundations take one card at a time", ErrIllegalMove)
+	}
+
+	card := moved[0]
+	if suitOf(card) != suit {
+		return fmt.Errorf("%w: %s does not belong on the %s foundation", ErrIllegalMove, card, suit)
+	}
+	if len(foundation) == 0 {
+		if aceLow.Rank(card) != 1 {
+			return fmt.Errorf("%w: a foundation starts with an ace", ErrIllegalMove)
+		}
+		return nil
+	}
+
+	top := foundation[len(foundation)-1]
+	if aceLow.Rank(card) != aceLow.Rank(top)+1 {
+		return fmt.Errorf("%w: %s does not follow %s", ErrIllegalMove, card, top)
+	}
+	return nil
+}
+
+// canBuildTableau accepts a king on an empty column and otherwise a card one
+// rank down in the other colour
+func canBuildTableau(column []string, card string) error {
+	if len(column) == 0 {
+		if aceLow.Rank(card) != 13 {
+			return fmt.Errorf("%w: only a king can fill an empty column", ErrIllegalMove)
+		}
+		return nil
+	}
+
+	top := column[len(column)-1]
+	if colorOf(top) == colorOf(card) || aceLow.Rank(card) != aceLow.Rank(top)-1 {
+		return fmt.Errorf("%w: %s cannot go on %s", ErrIllegalMove, card, top)
+	}
+	return nil
+}
+
+func suitOf(code string) string {
+	return code[len(code)-1:]
+}
+
+func colorOf(code string) string {
+	return aceLow.Annotate(model.CardFromCode(code)).Color
+}
+
+// Implement BeforeSave hook to encode the layout to JSON
+func (g *Game) BeforeSave(*gorm.DB) error {
+	var err error
+	g.StateJSON, err = json.Marshal(state{Tableau: g.Tableau, Foundations: g.Foundations, Stock: g.Stock, Waste: g.Waste})
+	return err
+}
+
+// Implement AfterFind hook to decode the layout from JSON
+func (g *Game) AfterFind(*gorm.DB) error {
+	if len(g.StateJSON) == 0 {
+		return nil
+	}
+
+	saved := state{}
+	if err := json.Unmarshal(g.StateJSON, &saved); err != nil {
+		return err
+	}
+	g.Tableau, g.Foundations, g.Stock, g.Waste = saved.Tableau, saved.Foundations, saved.Stock, saved.Waste
+	return nil
+}
@@ -0,0 +1,140 @@
+package solitaire_test
+
+import (
+	"errors"
+	"testing"
+	"toggl-test-wiliam/game/solitaire"
+	"toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var values = []string{"A", "2", "3", "4", "5", "6", "7", "8", "9", "10", "J", "Q", "K"}
+
+// stack deals the given codes in order
+func stack(codes ...string) solitaire.Drawer {
+	return func() (model.Card, error) {
+		if len(codes) == 0 {
+			return model.Card{}, errors.New("stack is empty")
+		}
+		card := model.CardFromCode(codes[0])
+		codes = codes[1:]
+		return card, nil
+	}
+}
+
+func fullDeck() []string {
+	codes := []string{}
+	for _, suit := range solitaire.Suits {
+		for _, value := range values {
+			codes = append(codes, value+suit)
+		}
+	}
+	return codes
+}
+
+func TestGame_Deal(t *testing.T) {
+	game, err := solitaire.NewGame("deck", 1)
+	require.NoError(t, err)
+
+	codes := fullDeck()
+	require.NoError(t, game.Deal(stack(codes...)))
+
+	for col, column := range game.Tableau {
+		assert.Len(t, column.Cards, col+1)
+		assert.Equal(t, col, column.Hidden)
+	}
+	// The first row takes the first seven cards, one per column
+	assert.Equal(t, "AC", game.Tableau[0].Cards[0])
+	assert.Equal(t, []string{"7C", "KC"}, game.Tableau[6].Cards[:2])
+	assert.Len(t, game.Stock, 24)
+	assert.Equal(t, "KS", game.Stock[23])
+
+	game, _ = solitaire.NewGame("deck", 1)
+	assert.ErrorIs(t, game.Deal(stack(codes[:30]...)), solitaire.ErrDeckTooSmall)
+
+	_, err = solitaire.NewGame("deck", 2)
+	assert.Error(t, err)
+}
+
+func TestGame_DrawAndRecycle(t *testing.T) {
+	game, _ := solitaire.NewGame("deck", 3)
+	game.Stock = []string{"AC", "2C", "3C", "4C"}
+
+	require.NoError(t, game.Draw())
+	assert.Equal(t, []string{"4C", "3C", "2C"}, game.Waste)
+	require.NoError(t, game.Draw())
+	assert.Equal(t, []string{"4C", "3C", "2C", "AC"}, game.Waste)
+	assert.Empty(t, game.Stock)
+
+	// The waste turns back over into the stock in its original order
+	require.NoError(t, game.Draw())
+	assert.Equal(t, []string{"AC", "2C", "3C", "4C"}, game.Stock)
+	assert.Empty(t, game.Waste)
+	assert.Equal(t, 1, game.Passes)
+	assert.Equal(t, 3, game.Moves)
+
+	game.Stock = []string{}
+	assert.ErrorIs(t, game.Draw(), solitaire.ErrStockEmpty)
+}
+
+func TestGame_TableauMoves(t *testing.T) {
+	game, _ := solitaire.NewGame("deck", 1)
+	game.Tableau[0] = solitaire.Column{Cards: []string{"2S", "9H"}, Hidden: 1}
+	game.Tableau[1] = solitaire.Column{Cards: []string{"2D", "10C"}, Hidden: 1}
+	game.Tableau[2] = solitaire.Column{Cards: []string{"JC"}}
+	game.Tableau[3] = solitaire.Column{Cards: []string{}}
+	game.Waste = []string{"KH", "8S"}
+
+	// Same colour, wrong rank, face down cards and empty columns are refused
+	assert.ErrorIs(t, game.Move("tableau:1", "tableau:2", 1), solitaire.ErrIllegalMove)
+	assert.ErrorIs(t, game.Move("tableau:0", "tableau:2", 1), solitaire.ErrIllegalMove)
+	assert.ErrorIs(t, game.Move("tableau:1", "tableau:2", 2), solitaire.ErrIllegalMove)
+	assert.ErrorIs(t, game.Move("tableau:0", "tableau:3", 1), solitaire.ErrIllegalMove)
+	assert.ErrorIs(t, game.Move("waste", "tableau:9", 1), solitaire.ErrUnknownPile)
+	assert.ErrorIs(t, game.Move("stock", "tableau:0", 1), solitaire.ErrUnknownPile)
+
+	require.NoError(t, game.Move("waste", "tableau:0", 1))
+	assert.Equal(t, []string{"2S", "9H", "8S"}, game.Tableau[0].Cards)
+
+	require.NoError(t, game.Move("waste", "tableau:3", 1))
+	assert.Equal(t, []string{"KH"}, game.Tableau[3].Cards)
+
+	// Moving the run off uncovers the card below it
+	require.NoError(t, game.Move("tableau:0", "tableau:1", 2))
+	assert.Equal(t, []string{"2D", "10C", "9H", "8S"}, game.Tableau[1].Cards)
+	assert.Equal(t, []string{"2S"}, game.Tableau[0].Cards)
+	assert.Equal(t, 0, game.Tableau[0].Hidden)
+	assert.Equal(t, 3, game.Moves)
+}
+
+func TestGame_FoundationsAndWin(t *testing.T) {
+	game, _ := solitaire.NewGame("deck", 1)
+	game.Tableau[0] = solitaire.Column{Cards: []string{"2C"}}
+	game.Waste = []string{"AC"}
+
+	assert.ErrorIs(t, game.Move("tableau:0", "foundation", 1), solitaire.ErrIllegalMove)
+	assert.ErrorIs(t, game.Move("waste", "foundation:D", 1), solitaire.ErrIllegalMove)
+	require.NoError(t, game.Move("waste", "foundation", 1))
+	require.NoError(t, game.Move("tableau:0", "foundation:C", 1))
+	assert.Equal(t, []string{"AC", "2C"}, game.Foundations[0].Cards)
+
+	// Cards can come back down from a foundation onto the tableau
+	game.Tableau[1] = solitaire.Column{Cards: []string{"3H"}}
+	require.NoError(t, game.Move("foundation:C", "tableau:1", 1))
+	assert.Equal(t, []string{"3H", "2C"}, game.Tableau[1].Cards)
+
+	for i, suit := range solitaire.Suits {
+		game.Foundations[i].Cards = []string{}
+		for _, value := range values {
+			game.Foundations[i].Cards = append(game.Foundations[i].Cards, value+suit)
+		}
+	}
+	game.Foundations[3].Cards = game.Foundations[3].Cards[:12]
+	game.Waste = []string{"KS"}
+
+	require.NoError(t, game.Move("waste", "foundation", 1))
+	assert.Equal(t, solitaire.StatusWon, game.Status)
+	assert.ErrorIs(t, game.Draw(), solitaire.ErrGameWon)
+}
@@ -2,14 +2,15 @@ package main
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"time"
 	"toggl-test-wiliam/api"
-	"toggl-test-wiliam/model"
 	"toggl-test-wiliam/seeds"
 
-	"github.com/gorilla/mux"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -20,28 +21,70 @@ func main() {
 		panic("failed to connect database")
 	}
 
-	// Seeds the database with a full deck of cards
-	if err = db.AutoMigrate(&model.Card{}, &model.Deck{}); err == nil && db.Migrator().HasTable(&model.Card{}) {
-		if err := db.First(&model.Card{}).Error; errors.Is(err, gorm.ErrRecordNotFound) {
-			seeds.FrenchCardDeck(db)
-		}
+	// Migrates the schema and seeds the database with a full deck of cards
+	if err = seeds.Setup(db); err != nil {
+		panic("failed to migrate database")
 	}
 
-	dbMiddleware := func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Pass the database connection to the context
-			ctx := context.WithValue(r.Context(), "db", db)
-			next.ServeHTTP(w, r.WithContext(ctx))
-		})
-	}
+	server := api.NewServer(db)
 
-	r := mux.NewRouter()
+	// DECK_TTL overrides how long decks live when created without a "ttl" parameter
+	if ttl := os.Getenv("DECK_TTL"); ttl != "" {
+		server.DefaultTTL, err = time.ParseDuration(ttl)
+		if err != nil {
+			panic("invalid DECK_TTL")
+		}
+	}
+	// CARD_IMAGE_BASE_URL points the card image links away from the bundled SVGs
+	if baseURL := os.Getenv("CARD_IMAGE_BASE_URL"); baseURL != "" {
+		server.ImageBaseURL = baseURL
+	}
+	// API_KEYS ("name:key,...") and JWT_SECRET turn on authentication
+	if keys := os.Getenv("API_KEYS"); keys != "" {
+		server.APIKeys, err = api.ParseAPIKeys(keys)
+		if err != nil {
+			panic("invalid API_KEYS")
+		}
+	}
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		server.JWTSecret = []byte(secret)
+	}
+	// RATE_LIMIT_CREATE and RATE_LIMIT_DRAW ("rate,burst", or "0" for none) throttle each client
+	if limit := os.Getenv("RATE_LIMIT_CREATE"); limit != "" {
+		if server.CreateLimit, err = api.ParseRateLimit(limit); err != nil {
+			panic("invalid RATE_LIMIT_CREATE")
+		}
+	}
+	if limit := os.Getenv("RATE_LIMIT_DRAW"); limit != "" {
+		if server.DrawLimit, err = api.ParseRateLimit(limit); err != nil {
+			panic("invalid RATE_LIMIT_DRAW")
+		}
+	}
+	// TENANT_LIVE_DECKS caps the live decks of each tenant without a quota of its own
+	if liveDecks := os.Getenv("TENANT_LIVE_DECKS"); liveDecks != "" {
+		if server.TenantQuota.LiveDecks, err = strconv.Atoi(liveDecks); err != nil {
+			panic("invalid TENANT_LIVE_DECKS")
+		}
+	}
+	// WEBHOOK_ALLOW_PRIVATE lets webhooks call back services on a local network
+	if allow := os.Getenv("WEBHOOK_ALLOW_PRIVATE"); allow != "" {
+		if server.WebhookAllowPrivate, err = strconv.ParseBool(allow); err != nil {
+			panic("invalid WEBHOOK_ALLOW_PRIVATE")
+		}
+	}
+	server.StartJanitor(context.Background(), time.Minute)
+	server.StartWebhookRetries(context.Background(), time.Second)
 
-	r.Use(dbMiddleware)
-	r.HandleFunc("/deck", api.CreateNewDeck).Methods("POST")
-	r.HandleFunc("/deck/{deck_id}", api.OpenDeck).Methods("GET")
-	r.HandleFunc("/deck/{deck_id}/draw", api.DrawCards).Methods("GET")
+	// GRPC_ADDR (e.g. ":9090") turns on the gRPC API
+	if grpcAddr := os.Getenv("GRPC_ADDR"); grpcAddr != "" {
+		listener, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			panic("failed to listen on GRPC_ADDR")
+		}
+		go server.GRPCServer().Serve(listener)
+		fmt.Printf("Serving gRPC on %s....\n", grpcAddr)
+	}
 
 	fmt.Println("Listening on port 80....")
-	http.ListenAndServe(":80", r)
+	http.ListenAndServe(":80", server)
 }
@@ -1,9 +1,7 @@
 package main_test
 
 import (
-	"context"
 	"encoding/json"
-	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -12,7 +10,6 @@ import (
 	model "toggl-test-wiliam/model"
 	seeds "toggl-test-wiliam/seeds"
 
-	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -22,29 +19,10 @@ func TestIntegration(t *testing.T) {
 	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
 	assert.NoError(t, err)
 
-	// Seeds the database with a full deck of cards
-	if err = db.AutoMigrate(&model.Card{}, &model.Deck{}); err == nil && db.Migrator().HasTable(&model.Card{}) {
-		if err := db.First(&model.Card{}).Error; errors.Is(err, gorm.ErrRecordNotFound) {
-			seeds.FrenchCardDeck(db)
-		}
-	}
-
-	dbMiddleware := func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Pass the database connection to the context
-			ctx := context.WithValue(r.Context(), "db", db)
-			next.ServeHTTP(w, r.WithContext(ctx))
-		})
-	}
-
-	r := mux.NewRouter()
-	r.Use(dbMiddleware)
-
-	r.HandleFunc("/deck", api.CreateNewDeck).Methods("POST")
-	r.HandleFunc("/deck/{deck_id}", api.OpenDeck).Methods("GET")
-	r.HandleFunc("/deck/{deck_id}/draw", api.DrawCards).Methods("GET")
-
-	ts := httptest.NewServer(r)
+	// Migrates the schema and seeds the database with a full deck of cards
+	assert.NoError(t, seeds.Setup(db))
+
+	ts := httptest.NewServer(api.NewServer(db))
 	defer ts.Close()
 
 	var count int64
@@ -89,5 +67,5 @@ func TestIntegration(t *testing.T) {
 	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&deck))
 	assert.Equal(t, len(cards)+deck.Remaining, beforeDrawn)
 
-	db.Migrator().DropTable(&model.Card{}, &model.Deck{})
+	db.Migrator().DropTable(&model.Card{}, &model.Deck{}, &model.DeckEvent{})
 }
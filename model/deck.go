@@ -12,11 +12,24 @@ import (
 
 type Deck struct {
 	gorm.Model
-	ID        string `json:"deck_id"`
-	Shuffled  bool   `json:"shuffled"`
-	Remaining int    `json:"remaining"`
-	CardsJSON []byte `json:"cards_json" gorm:"column:cards"`
-	Cards     []Card `json:"cards" gorm:"-"`
+	ID         string          `json:"deck_id"`
+	Owner      string          `json:"owner" gorm:"index"`
+	CreatedBy  string          `json:"created_by" gorm:"index"`
+	Tenant     string          `json:"tenant" gorm:"index"`
+	SessionID  string          `json:"session_id" gorm:"index"`
+	GameID     string          `json:"game_id" gorm:"index"`
+	Visibility string          `json:"visibility"`
+	Protected  bool            `json:"protected"`
+	Shuffled   bool            `json:"shuffled"`
+	Remaining  int             `json:"remaining"`
+	ExpiresAt  *time.Time      `json:"expires_at" gorm:"index"`
+	CardType   string          `json:"card_type"`
+	Metadata   json.RawMessage `json:"metadata"`
+	CardsJSON  []byte          `json:"cards_json" gorm:"column:cards"`
+	Cards      []Card          `json:"cards" gorm:"-"`
+	// Piles holds the codes drawn onto each pile, top card last, as rebuilt
+	// by Replay; the piles themselves are stored apart
+	Piles map[string][]string `json:"-" gorm:"-"`
 }
 
 type Card struct {
@@ -24,11 +37,22 @@ type Card struct {
 	Suit     string `json:"suit"`
 	Code     string `json:"code"`
 	CardType string `json:"-"`
+	Tenant   string `json:"-" gorm:"index"`
+	Image    string `json:"image,omitempty" gorm:"-"`
+	Glyph    string `json:"glyph,omitempty" gorm:"-"`
+	Rank     int    `json:"rank,omitempty" gorm:"-"`
+	Color    string `json:"color,omitempty" gorm:"-"`
+	Points   *int   `json:"points,omitempty" gorm:"-"`
+	Hidden   bool   `json:"hidden,omitempty" gorm:"-"`
 }
 
 var maxCards = map[string]int{
 	"FRENCH": 52,
 }
+
+// MaxCustomDeckCards caps decks of card types that are not built in
+const MaxCustomDeckCards = 500
+
 var minCards = map[string]int{
 	"FRENCH": 1,
 }
@@ -65,32 +89,64 @@ func (d *Deck) Create(cardCodes []string) (Deck, error) {
 
 	deck := Deck{}
 	deck.ID = uuid.New().String()
+	deck.CardType = "FRENCH"
 
 	for _, code := range cardCodes {
-		value := ""
-		suit := ""
-
-		if len(code) <= 2 {
-			value = code[0:1]
-			suit = code[1:2]
-		} else {
-			value = code[0:2]
-			suit = code[2:3]
-		}
+		deck.Cards = append(deck.Cards, CardFromCode(code))
+	}
+	deck.Remaining = len(deck.Cards)
 
-		card := Card{
-			Code:     code,
-			Suit:     suitNames[suit],
-			Value:    valueNames[value],
-			CardType: "FRENCH",
-		}
-		deck.Cards = append(deck.Cards, card)
+	return deck, nil
+}
+
+// CreateOfType builds a deck of a custom card type from catalog cards, in
+// order, as their codes do not spell out their value and suit
+func (d *Deck) CreateOfType(cardType string, cards []Card) (Deck, error) {
+	if len(cards) < 1 {
+		return Deck{}, errors.New("too few cards provided")
+	} else if len(cards) > MaxCustomDeckCards {
+		return Deck{}, errors.New("too many cards provided")
 	}
+
+	deck := Deck{}
+	deck.ID = uuid.New().String()
+	deck.CardType = cardType
+	deck.Cards = append([]Card{}, cards...)
 	deck.Remaining = len(deck.Cards)
 
 	return deck, nil
 }
 
+// CardFromCode spells out the value and suit of a French card code such as "10H"
+func CardFromCode(code string) Card {
+	value, suit := splitCode(code)
+
+	return Card{
+		Code:     code,
+		Suit:     suitNames[suit],
+		Value:    valueNames[value],
+		CardType: "FRENCH",
+	}
+}
+
+// IsCardCode reports whether code names a French card, e.g. "10H"
+func IsCardCode(code string) bool {
+	if len(code) < 2 || len(code) > 3 {
+		return false
+	}
+
+	value, suit := splitCode(code)
+	return valueNames[value] != "" && suitNames[suit] != ""
+}
+
+// splitCode separates the value from the suit letter, e.g. "10H" into "10" and "H"
+func splitCode(code string) (string, string) {
+	if len(code) <= 2 {
+		return code[0:1], code[1:2]
+	}
+	return code[0:2], code[2:3]
+}
+
 func (d *Deck) Draw(count int) ([]Card, error) {
 	if count > d.Remaining {
 		return []Card{}, errors.New("too many cards requested")
@@ -104,18 +160,35 @@ func (d *Deck) Draw(count int) ([]Card, error) {
 }
 
 func (d *Deck) Shuffle() {
-	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(len(d.Cards), func(i, j int) {
+	d.ShuffleSeed(time.Now().UnixNano())
+}
+
+// ShuffleSeed shuffles the remaining cards with a deterministic RNG, so the
+// same seed over the same order always yields the same result
+func (d *Deck) ShuffleSeed(seed int64) {
+	rng := rand.New(rand.NewSource(seed))
+	rng.Shuffle(len(d.Cards), func(i, j int) {
 		d.Cards[i], d.Cards[j] = d.Cards[j], d.Cards[i]
 	})
 
 	d.Shuffled = true
 }
 
+// Expired reports whether the deck outlived its TTL; decks without one never expire
+func (d *Deck) Expired(now time.Time) bool {
+	return d.ExpiresAt != nil && !now.Before(*d.ExpiresAt)
+}
+
+// VisibleTo reports whether the caller may see the remaining order of the deck
+func (d *Deck) VisibleTo(caller string) bool {
+	return Visible(d.Visibility, d.Owner, caller)
+}
+
 // Implement BeforeSave hook to encode Cards field to JSON
 func (d *Deck) BeforeSave(*gorm.DB) error {
 	var err error
-	if len(d.Cards) > 0 {
+	// A drawn-out deck still holds an empty, non-nil slice that must be persisted
+	if d.Cards != nil {
 		d.CardsJSON, err = json.Marshal(d.Cards)
 		if err != nil {
 			return err
@@ -2,6 +2,7 @@ package model_test
 
 import (
 	"testing"
+	"time"
 	"toggl-test-wiliam/model"
 
 	"github.com/stretchr/testify/assert"
@@ -135,3 +136,34 @@ func TestDeck_AfterFind(t *testing.T) {
 	}
 	assert.Equal(t, expectedCards, deck.Cards)
 }
+
+func TestShuffleSeed_Deterministic(t *testing.T) {
+	cardCodes := []string{"AS", "KS", "QS", "JS", "10S", "9S", "8S", "7S", "6S", "5S", "4S", "3S", "2S"}
+
+	first, _ := (&model.Deck{}).Create(cardCodes)
+	second, _ := (&model.Deck{}).Create(cardCodes)
+	first.ShuffleSeed(42)
+	second.ShuffleSeed(42)
+
+	assert.True(t, first.Shuffled)
+	assert.Equal(t, first.Cards, second.Cards)
+}
+
+func TestDeck_Expired(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Second)
+	future := now.Add(time.Second)
+
+	assert.False(t, (&model.Deck{}).Expired(now))
+	assert.True(t, (&model.Deck{ExpiresAt: &past}).Expired(now))
+	assert.False(t, (&model.Deck{ExpiresAt: &future}).Expired(now))
+}
+
+func TestIsCardCode(t *testing.T) {
+	assert.True(t, model.IsCardCode("AS"))
+	assert.True(t, model.IsCardCode("10H"))
+	assert.False(t, model.IsCardCode("1H"))
+	assert.False(t, model.IsCardCode("AX"))
+	assert.False(t, model.IsCardCode("10HH"))
+	assert.False(t, model.IsCardCode(""))
+}
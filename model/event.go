@@ -0,0 +1,95 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	EventCreated  = "created"
+	EventShuffled = "shuffled"
+	EventDrawn    = "drawn"
+	EventUndone   = "undone"
+	EventSorted   = "sorted"
+)
+
+// DeckEvent is one entry of a deck's append-only operation log. Piles only
+// holds the cards of the piles an imported deck came with
+type DeckEvent struct {
+	ID           uint                `json:"-" gorm:"primarykey"`
+	DeckID       string              `json:"deck_id" gorm:"uniqueIndex:idx_deck_events_seq"`
+	Seq          int                 `json:"event_no" gorm:"uniqueIndex:idx_deck_events_seq"`
+	Type         string              `json:"type"`
+	Count        int                 `json:"count,omitempty"`
+	Undoes       int                 `json:"undoes,omitempty"`
+	Shuffled     bool                `json:"shuffled,omitempty"`
+	ClonedFrom   string              `json:"cloned_from,omitempty"`
+	ImportedFrom string              `json:"imported_from,omitempty"`
+	SortBy       string              `json:"sort_by,omitempty"`
+	Rules        string              `json:"rules,omitempty"`
+	Pile         string              `json:"pile,omitempty"`
+	Seed         int64               `json:"-"`
+	CardsJSON    []byte              `json:"-" gorm:"column:cards"`
+	Cards        []string            `json:"cards,omitempty" gorm:"-"`
+	PilesJSON    []byte              `json:"-" gorm:"column:piles"`
+	Piles        map[string][]string `json:"piles,omitempty" gorm:"-"`
+	CreatedAt    time.Time           `json:"created_at"`
+}
+
+// AppendEvent stores the event as the next entry in its deck's log
+func AppendEvent(db *gorm.DB, event *DeckEvent) error {
+	var last int
+	err := db.Model(&DeckEvent{}).
+		Where("deck_id = ?", event.DeckID).
+		Select("COALESCE(MAX(seq), 0)").
+		Scan(&last).Error
+	if err != nil {
+		return err
+	}
+
+	event.Seq = last + 1
+	return db.Create(event).Error
+}
+
+// Events returns the log of a deck in the order the operations happened
+func Events(db *gorm.DB, deckID string) ([]DeckEvent, error) {
+	var events []DeckEvent
+	err := db.Where("deck_id = ?", deckID).Order("seq").Find(&events).Error
+	return events, err
+}
+
+// CardCodes lists the codes of the given cards in order
+func CardCodes(cards []Card) []string {
+	codes := make([]string, 0, len(cards))
+	for _, card := range cards {
+		codes = append(codes, card.Code)
+	}
+	return codes
+}
+
+// Implement BeforeSave hook to encode Cards field to JSON
+func (e *DeckEvent) BeforeSave(*gorm.DB) error {
+	var err error
+	if e.Cards != nil {
+		e.CardsJSON, err = json.Marshal(e.Cards)
+	}
+	if err == nil && e.Piles != nil {
+		e.PilesJSON, err = json.Marshal(e.Piles)
+	}
+	return err
+}
+
+// Implement AfterFind hook to decode Cards field from JSON
+func (e *DeckEvent) AfterFind(*gorm.DB) error {
+	if len(e.CardsJSON) > 0 {
+		if err := json.Unmarshal(e.CardsJSON, &e.Cards); err != nil {
+			return err
+		}
+	}
+	if len(e.PilesJSON) > 0 {
+		return json.Unmarshal(e.PilesJSON, &e.Piles)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package model_test
+
+import (
+	"testing"
+	"toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestAppendEvent_NumbersPerDeck(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.DeckEvent{}))
+
+	first := model.DeckEvent{DeckID: "first", Type: model.EventCreated, Cards: []string{"AS", "KS"}}
+	other := model.DeckEvent{DeckID: "other", Type: model.EventCreated, Cards: []string{"AH"}}
+	drawn := model.DeckEvent{DeckID: "first", Type: model.EventDrawn, Count: 1, Cards: []string{"KS"}}
+
+	require.NoError(t, model.AppendEvent(db, &first))
+	require.NoError(t, model.AppendEvent(db, &other))
+	require.NoError(t, model.AppendEvent(db, &drawn))
+
+	assert.Equal(t, 1, first.Seq)
+	assert.Equal(t, 1, other.Seq)
+	assert.Equal(t, 2, drawn.Seq)
+
+	events, err := model.Events(db, "first")
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, []string{"AS", "KS"}, events[0].Cards)
+	assert.Equal(t, []string{"KS"}, events[1].Cards)
+
+	db.Migrator().DropTable(&model.DeckEvent{})
+}
@@ -0,0 +1,42 @@
+package model
+
+// Offsets into the Unicode "Playing Cards" block, where every suit starts at
+// its own row and the knight (C) sits between the jack and the queen
+var glyphSuits = map[string]rune{
+	"S": 0x1F0A0,
+	"H": 0x1F0B0,
+	"D": 0x1F0C0,
+	"C": 0x1F0D0,
+}
+
+var glyphValues = map[string]rune{
+	"A":  0x1,
+	"2":  0x2,
+	"3":  0x3,
+	"4":  0x4,
+	"5":  0x5,
+	"6":  0x6,
+	"7":  0x7,
+	"8":  0x8,
+	"9":  0x9,
+	"10": 0xA,
+	"J":  0xB,
+	"Q":  0xD,
+	"K":  0xE,
+}
+
+// CardGlyph returns the Unicode playing card for a French card code, e.g. 🂡
+// for "AS", or an empty string when the code is not a French card
+func CardGlyph(code string) string {
+	if len(code) < 2 {
+		return ""
+	}
+
+	value, suit := splitCode(code)
+	base, ok := glyphSuits[suit]
+	offset, found := glyphValues[value]
+	if !ok || !found {
+		return ""
+	}
+	return string(base + offset)
+}
@@ -0,0 +1,18 @@
+package model_test
+
+import (
+	"testing"
+	"toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCardGlyph(t *testing.T) {
+	assert.Equal(t, "🂡", model.CardGlyph("AS"))
+	assert.Equal(t, "🂺", model.CardGlyph("10H"))
+	assert.Equal(t, "🃋", model.CardGlyph("JD"))
+	assert.Equal(t, "🃝", model.CardGlyph("QC"))
+	assert.Equal(t, "🂮", model.CardGlyph("KS"))
+	assert.Equal(t, "", model.CardGlyph("XX"))
+	assert.Equal(t, "", model.CardGlyph("A"))
+}
@@ -0,0 +1,93 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// VisibilityPublic cards can be seen by everyone
+	VisibilityPublic = "PUBLIC"
+	// VisibilityOwner cards can only be seen by the owner of the deck or pile
+	VisibilityOwner = "OWNER"
+	// VisibilityFaceDown cards cannot be seen by anyone, the owner included
+	VisibilityFaceDown = "FACE_DOWN"
+)
+
+// ParseVisibility accepts public, owner or face_down in any case, defaulting to public
+func ParseVisibility(name string) (string, error) {
+	switch visibility := strings.ToUpper(name); visibility {
+	case "":
+		return VisibilityPublic, nil
+	case VisibilityPublic, VisibilityOwner, VisibilityFaceDown:
+		return visibility, nil
+	default:
+		return "", fmt.Errorf("invalid visibility: %s", name)
+	}
+}
+
+// Visible reports whether the caller may see cards with the given visibility
+// and owner; decks stored before visibilities existed count as public
+func Visible(visibility, owner, caller string) bool {
+	switch visibility {
+	case "", VisibilityPublic:
+		return true
+	case VisibilityOwner:
+		return owner != "" && owner == caller
+	default:
+		return false
+	}
+}
+
+// Pile is a named set of cards drawn off a deck, such as a player's hand or a
+// discard pile, with the newest card last
+type Pile struct {
+	ID         uint      `json:"-" gorm:"primarykey"`
+	DeckID     string    `json:"deck_id" gorm:"uniqueIndex:idx_piles_name"`
+	Name       string    `json:"name" gorm:"uniqueIndex:idx_piles_name"`
+	Owner      string    `json:"owner,omitempty"`
+	Visibility string    `json:"visibility"`
+	CardsJSON  []byte    `json:"-" gorm:"column:cards"`
+	Cards      []string  `json:"cards" gorm:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// VisibleTo reports whether the caller may see the cards of the pile
+func (p *Pile) VisibleTo(caller string) bool {
+	return Visible(p.Visibility, p.Owner, caller)
+}
+
+// Takeback removes the given cards from the top of the pile, failing when
+// they are no longer there
+func (p *Pile) Takeback(codes []string) error {
+	top := len(p.Cards) - len(codes)
+	if top < 0 || strings.Join(p.Cards[top:], ",") != strings.Join(codes, ",") {
+		return fmt.Errorf("pile %s no longer holds %v on top", p.Name, codes)
+	}
+	p.Cards = p.Cards[:top]
+	return nil
+}
+
+// Implement BeforeSave hook to encode the cards to JSON
+func (p *Pile) BeforeSave(*gorm.DB) error {
+	if p.Cards == nil {
+		p.Cards = []string{}
+	}
+
+	var err error
+	p.CardsJSON, err = json.Marshal(p.Cards)
+	return err
+}
+
+// Implement AfterFind hook to decode the cards from JSON
+func (p *Pile) AfterFind(*gorm.DB) error {
+	if len(p.CardsJSON) == 0 {
+		return nil
+	}
+	return json.Unmarshal(p.CardsJSON, &p.Cards)
+}
@@ -0,0 +1,43 @@
+package model_test
+
+import (
+	"testing"
+	"toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVisibility(t *testing.T) {
+	for name, expected := range map[string]string{
+		"":          model.VisibilityPublic,
+		"public":    model.VisibilityPublic,
+		"owner":     model.VisibilityOwner,
+		"FACE_DOWN": model.VisibilityFaceDown,
+	} {
+		visibility, err := model.ParseVisibility(name)
+		require.NoError(t, err)
+		assert.Equal(t, expected, visibility)
+	}
+
+	_, err := model.ParseVisibility("secret")
+	assert.EqualError(t, err, "invalid visibility: secret")
+}
+
+func TestVisible(t *testing.T) {
+	assert.True(t, model.Visible("", "", ""))
+	assert.True(t, model.Visible(model.VisibilityPublic, "alice", "bob"))
+	assert.True(t, model.Visible(model.VisibilityOwner, "alice", "alice"))
+	assert.False(t, model.Visible(model.VisibilityOwner, "alice", "bob"))
+	assert.False(t, model.Visible(model.VisibilityOwner, "", ""))
+	assert.False(t, model.Visible(model.VisibilityFaceDown, "alice", "alice"))
+}
+
+func TestPile_Takeback(t *testing.T) {
+	pile := model.Pile{Name: "discard", Cards: []string{"AS", "KD", "2C"}}
+
+	assert.Error(t, pile.Takeback([]string{"AS"}))
+	require.NoError(t, pile.Takeback([]string{"KD", "2C"}))
+	assert.Equal(t, []string{"AS"}, pile.Cards)
+	assert.Error(t, pile.Takeback([]string{"QH", "AS"}))
+}
@@ -0,0 +1,144 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNothingToUndo is returned when a deck has no operation left to revert
+var ErrNothingToUndo = errors.New("nothing to undo")
+
+// snapshot is the state of a deck right after the event numbered seq
+type snapshot struct {
+	seq  int
+	deck Deck
+}
+
+// Replay rebuilds a deck by applying its events in order, starting from the
+// card order recorded when it was created, and the piles its draws went to.
+// A log that contradicts itself, such as a draw returning other cards than
+// the replayed order holds, is an error.
+func Replay(events []DeckEvent) (Deck, error) {
+	states, err := replay(events)
+	if err != nil {
+		return Deck{}, err
+	}
+	return states[len(states)-1].deck, nil
+}
+
+// UndoTarget returns the number of the event an undo appended to the log would revert
+func UndoTarget(events []DeckEvent) (int, error) {
+	// Decks created before the event log existed have nothing to revert to
+	if len(events) == 0 {
+		return 0, ErrNothingToUndo
+	}
+
+	states, err := replay(events)
+	if err != nil {
+		return 0, err
+	}
+	if len(states) < 2 {
+		return 0, ErrNothingToUndo
+	}
+	return states[len(states)-1].seq, nil
+}
+
+// replay keeps every intermediate state on a stack, so that an undo event
+// can restore the previous one by popping the top
+func replay(events []DeckEvent) ([]snapshot, error) {
+	if len(events) == 0 || events[0].Type != EventCreated {
+		return nil, fmt.Errorf("event log does not start with a %q event", EventCreated)
+	}
+
+	var states []snapshot
+	deck := Deck{}
+	for _, event := range events {
+		if event.Type == EventUndone {
+			if len(states) < 2 {
+				return nil, fmt.Errorf("event %d: %w", event.Seq, ErrNothingToUndo)
+			}
+			states = states[:len(states)-1]
+			deck = states[len(states)-1].deck.detach()
+			continue
+		}
+
+		if err := deck.apply(event); err != nil {
+			return nil, fmt.Errorf("event %d: %w", event.Seq, err)
+		}
+		states = append(states, snapshot{seq: event.Seq, deck: deck.detach()})
+	}
+	return states, nil
+}
+
+func (d *Deck) apply(event DeckEvent) error {
+	switch event.Type {
+	case EventCreated:
+		d.ID = event.DeckID
+		d.Shuffled = event.Shuffled
+		d.Piles = map[string][]string{}
+		for name, codes := range event.Piles {
+			d.Piles[name] = append([]string{}, codes...)
+		}
+		d.Cards = []Card{}
+		for _, code := range event.Cards {
+			d.Cards = append(d.Cards, CardFromCode(code))
+		}
+	case EventShuffled:
+		d.ShuffleSeed(event.Seed)
+	case EventDrawn:
+		drawn, err := d.Draw(event.Count)
+		if err != nil {
+			return err
+		}
+		if !sameCodes(CardCodes(drawn), event.Cards) {
+			return fmt.Errorf("drew %v but the log recorded %v", CardCodes(drawn), event.Cards)
+		}
+		if event.Pile != "" {
+			d.Piles[event.Pile] = append(d.Piles[event.Pile], event.Cards...)
+		}
+	case EventSorted:
+		rules, ok := LookupRules(event.Rules)
+		if !ok {
+			return fmt.Errorf("unknown rules %q", event.Rules)
+		}
+		keys := strings.Split(event.SortBy, ",")
+		if err := ValidateSortKeys(keys); err != nil {
+			return err
+		}
+		if event.Pile != "" {
+			sortCodes(d.Piles[event.Pile], keys, rules)
+			break
+		}
+		d.Sort(keys, rules)
+	default:
+		return fmt.Errorf("unknown event type %q", event.Type)
+	}
+
+	d.Remaining = len(d.Cards)
+	return nil
+}
+
+// detach copies the card slice, which Draw and Shuffle modify in place, and
+// the piles, which draws append to
+func (d Deck) detach() Deck {
+	d.Cards = append([]Card{}, d.Cards...)
+	piles := make(map[string][]string, len(d.Piles))
+	for name, codes := range d.Piles {
+		piles[name] = append([]string{}, codes...)
+	}
+	d.Piles = piles
+	return d
+}
+
+func sameCodes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
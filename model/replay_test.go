@@ -0,0 +1,113 @@
+package model_test
+
+import (
+	"testing"
+	"toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay_RebuildsDeck(t *testing.T) {
+	cardCodes := []string{"AS", "KS", "QS", "JS", "10S", "9S"}
+
+	expected, err := (&model.Deck{}).Create(cardCodes)
+	require.NoError(t, err)
+	expected.ShuffleSeed(7)
+	drawn, err := expected.Draw(2)
+	require.NoError(t, err)
+
+	deck, err := model.Replay([]model.DeckEvent{
+		{DeckID: "deck", Seq: 1, Type: model.EventCreated, Cards: cardCodes},
+		{DeckID: "deck", Seq: 2, Type: model.EventShuffled, Seed: 7},
+		{DeckID: "deck", Seq: 3, Type: model.EventDrawn, Count: 2, Cards: model.CardCodes(drawn)},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "deck", deck.ID)
+	assert.True(t, deck.Shuffled)
+	assert.Equal(t, 4, deck.Remaining)
+	assert.Equal(t, expected.Cards, deck.Cards)
+}
+
+func TestReplay_MissingCreatedEvent(t *testing.T) {
+	_, err := model.Replay([]model.DeckEvent{{Seq: 1, Type: model.EventShuffled, Seed: 7}})
+	assert.EqualError(t, err, `event log does not start with a "created" event`)
+}
+
+func TestReplay_InconsistentDraw(t *testing.T) {
+	_, err := model.Replay([]model.DeckEvent{
+		{Seq: 1, Type: model.EventCreated, Cards: []string{"AS", "KS"}},
+		{Seq: 2, Type: model.EventDrawn, Count: 1, Cards: []string{"AS"}},
+	})
+	assert.EqualError(t, err, "event 2: drew [KS] but the log recorded [AS]")
+}
+
+func TestReplay_UndoRestoresPreviousState(t *testing.T) {
+	cardCodes := []string{"AS", "KS", "QS", "JS"}
+	events := []model.DeckEvent{
+		{Seq: 1, Type: model.EventCreated, Cards: cardCodes},
+		{Seq: 2, Type: model.EventDrawn, Count: 1, Cards: []string{"JS"}},
+		{Seq: 3, Type: model.EventShuffled, Seed: 7},
+	}
+
+	target, err := model.UndoTarget(events)
+	require.NoError(t, err)
+	assert.Equal(t, 3, target)
+
+	events = append(events, model.DeckEvent{Seq: 4, Type: model.EventUndone, Undoes: 3})
+	deck, err := model.Replay(events)
+	require.NoError(t, err)
+	assert.False(t, deck.Shuffled)
+	assert.Equal(t, []string{"AS", "KS", "QS"}, model.CardCodes(deck.Cards))
+
+	target, err = model.UndoTarget(events)
+	require.NoError(t, err)
+	assert.Equal(t, 2, target)
+
+	events = append(events, model.DeckEvent{Seq: 5, Type: model.EventUndone, Undoes: 2})
+	deck, err = model.Replay(events)
+	require.NoError(t, err)
+	assert.Equal(t, 4, deck.Remaining)
+	assert.Equal(t, cardCodes, model.CardCodes(deck.Cards))
+
+	_, err = model.UndoTarget(events)
+	assert.ErrorIs(t, err, model.ErrNothingToUndo)
+}
+
+func TestReplay_RebuildsPiles(t *testing.T) {
+	events := []model.DeckEvent{
+		{Seq: 1, Type: model.EventCreated, Cards: []string{"AS", "KS", "QS", "JS"}},
+		{Seq: 2, Type: model.EventDrawn, Count: 2, Cards: []string{"QS", "JS"}, Pile: "hand:alice"},
+		{Seq: 3, Type: model.EventDrawn, Count: 1, Cards: []string{"KS"}, Pile: "discard"},
+		{Seq: 4, Type: model.EventDrawn, Count: 1, Cards: []string{"AS"}, Pile: "hand:alice"},
+	}
+
+	deck, err := model.Replay(events)
+	require.NoError(t, err)
+	assert.Equal(t, 0, deck.Remaining)
+	assert.Equal(t, map[string][]string{"hand:alice": {"QS", "JS", "AS"}, "discard": {"KS"}}, deck.Piles)
+
+	// Undoing a pile draw takes the cards back off the pile
+	deck, err = model.Replay(append(events, model.DeckEvent{Seq: 5, Type: model.EventUndone, Undoes: 4}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"AS"}, model.CardCodes(deck.Cards))
+	assert.Equal(t, map[string][]string{"hand:alice": {"QS", "JS"}, "discard": {"KS"}}, deck.Piles)
+}
+
+func TestReplay_SortsPiles(t *testing.T) {
+	events := []model.DeckEvent{
+		{Seq: 1, Type: model.EventCreated, Cards: []string{"2C", "AS", "KH", "3D"}},
+		{Seq: 2, Type: model.EventDrawn, Count: 3, Cards: []string{"AS", "KH", "3D"}, Pile: "hand"},
+		{Seq: 3, Type: model.EventSorted, SortBy: "rank", Rules: "ace_high", Pile: "hand"},
+	}
+
+	deck, err := model.Replay(events)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2C"}, model.CardCodes(deck.Cards))
+	assert.Equal(t, []string{"3D", "KH", "AS"}, deck.Piles["hand"])
+
+	deck, err = model.Replay(append(events, model.DeckEvent{Seq: 4, Type: model.EventUndone, Undoes: 3}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"AS", "KH", "3D"}, deck.Piles["hand"])
+}
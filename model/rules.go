@@ -0,0 +1,81 @@
+package model
+
+// Ruleset tells how a game ranks and scores cards
+type Ruleset struct {
+	Name    string
+	AceHigh bool
+	// Points maps a value letter such as "K" to what the card is worth, nil when the game has no card points
+	Points map[string]int
+}
+
+var faceValuePoints = map[string]int{
+	"A": 1, "2": 2, "3": 3, "4": 4, "5": 5, "6": 6, "7": 7, "8": 8, "9": 9, "10": 10, "J": 10, "Q": 10, "K": 10,
+}
+
+var rulesets = map[string]Ruleset{
+	"ace_high": {Name: "ace_high", AceHigh: true},
+	"ace_low":  {Name: "ace_low"},
+	"blackjack": {
+		Name:    "blackjack",
+		AceHigh: true,
+		Points: map[string]int{
+			"A": 11, "2": 2, "3": 3, "4": 4, "5": 5, "6": 6, "7": 7, "8": 8, "9": 9, "10": 10, "J": 10, "Q": 10, "K": 10,
+		},
+	},
+	"bridge": {
+		Name:    "bridge",
+		AceHigh: true,
+		Points: map[string]int{
+			"A": 4, "K": 3, "Q": 2, "J": 1,
+		},
+	},
+	"cribbage": {Name: "cribbage", Points: faceValuePoints},
+}
+
+var ranks = map[string]int{
+	"A": 1, "2": 2, "3": 3, "4": 4, "5": 5, "6": 6, "7": 7, "8": 8, "9": 9, "10": 10, "J": 11, "Q": 12, "K": 13,
+}
+
+var suitColors = map[string]string{
+	"C": "BLACK",
+	"S": "BLACK",
+	"D": "RED",
+	"H": "RED",
+}
+
+// LookupRules finds a ruleset by name: ace_high, ace_low, blackjack, bridge or cribbage
+func LookupRules(name string) (Ruleset, bool) {
+	rules, ok := rulesets[name]
+	return rules, ok
+}
+
+// Rank orders a French card code from 2 up to the king, with the ace either
+// below the 2 (1) or above the king (14); unknown codes rank 0
+func (r Ruleset) Rank(code string) int {
+	if len(code) < 2 {
+		return 0
+	}
+
+	value, _ := splitCode(code)
+	rank := ranks[value]
+	if rank == 1 && r.AceHigh {
+		return 14
+	}
+	return rank
+}
+
+// Annotate fills in the rank, colour and, for scoring games, points of a card
+func (r Ruleset) Annotate(card Card) Card {
+	if len(card.Code) < 2 {
+		return card
+	}
+
+	value, suit := splitCode(card.Code)
+	card.Rank = r.Rank(card.Code)
+	card.Color = suitColors[suit]
+	if r.Points != nil {
+		points := r.Points[value]
+		card.Points = &points
+	}
+	return card
+}
@@ -0,0 +1,50 @@
+package model_test
+
+import (
+	"testing"
+	"toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleset_Rank(t *testing.T) {
+	aceHigh, ok := model.LookupRules("ace_high")
+	require.True(t, ok)
+	aceLow, ok := model.LookupRules("ace_low")
+	require.True(t, ok)
+
+	assert.Equal(t, 14, aceHigh.Rank("AS"))
+	assert.Equal(t, 1, aceLow.Rank("AS"))
+	assert.Equal(t, 10, aceHigh.Rank("10H"))
+	assert.Equal(t, 13, aceLow.Rank("KD"))
+	assert.Equal(t, 0, aceHigh.Rank("ZZ"))
+}
+
+func TestRuleset_Annotate(t *testing.T) {
+	for name, expected := range map[string][]int{
+		"blackjack": {11, 10, 10, 7},
+		"bridge":    {4, 3, 0, 0},
+		"cribbage":  {1, 10, 10, 7},
+	} {
+		rules, ok := model.LookupRules(name)
+		require.True(t, ok)
+
+		var points []int
+		for _, code := range []string{"AS", "KH", "10D", "7C"} {
+			card := rules.Annotate(model.CardFromCode(code))
+			require.NotNil(t, card.Points)
+			points = append(points, *card.Points)
+		}
+		assert.Equal(t, expected, points, name)
+	}
+
+	rules, _ := model.LookupRules("ace_high")
+	card := rules.Annotate(model.CardFromCode("QH"))
+	assert.Equal(t, 12, card.Rank)
+	assert.Equal(t, "RED", card.Color)
+	assert.Nil(t, card.Points)
+
+	_, ok := model.LookupRules("canasta")
+	assert.False(t, ok)
+}
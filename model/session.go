@@ -0,0 +1,148 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	MinSessionSeats = 2
+	MaxSessionSeats = 10
+	// MaxSessionDecks caps how many decks a session is dealt from
+	MaxSessionDecks = 8
+)
+
+var (
+	ErrSessionFull   = errors.New("session is full")
+	ErrAlreadySeated = errors.New("player is already seated")
+	ErrNotSeated     = errors.New("player is not seated")
+	ErrNotYourTurn   = errors.New("not your turn")
+)
+
+// Seat is a player's place at a session, in turn order
+type Seat struct {
+	Player   string    `json:"player"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// Session groups the decks of a multiplayer game with the seated players and
+// whose turn it is
+type Session struct {
+	ID        string    `json:"session_id" gorm:"primaryKey"`
+	Tenant    string    `json:"-" gorm:"index"`
+	MaxSeats  int       `json:"max_seats"`
+	Turn      int       `json:"turn"`
+	DeckIDs   []string  `json:"deck_ids" gorm:"-"`
+	Seats     []Seat    `json:"seats" gorm:"-"`
+	DecksJSON []byte    `json:"-" gorm:"column:decks"`
+	SeatsJSON []byte    `json:"-" gorm:"column:seats"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewSession opens a session of up to maxSeats players over the given decks
+func NewSession(maxSeats int, deckIDs []string) (*Session, error) {
+	if maxSeats < MinSessionSeats || maxSeats > MaxSessionSeats {
+		return nil, errors.New("a session seats 2 to 10 players")
+	}
+
+	return &Session{
+		ID:       uuid.New().String(),
+		MaxSeats: maxSeats,
+		DeckIDs:  deckIDs,
+		Seats:    []Seat{},
+	}, nil
+}
+
+// Join seats the player after everyone already seated
+func (s *Session) Join(player string, now time.Time) error {
+	if s.seat(player) >= 0 {
+		return ErrAlreadySeated
+	}
+	if len(s.Seats) >= s.MaxSeats {
+		return ErrSessionFull
+	}
+
+	s.Seats = append(s.Seats, Seat{Player: player, JoinedAt: now})
+	return nil
+}
+
+// Leave frees the player's seat, keeping the turn with whoever held it or,
+// when the leaving player held it, passing it to the next seat
+func (s *Session) Leave(player string) error {
+	seat := s.seat(player)
+	if seat < 0 {
+		return ErrNotSeated
+	}
+
+	s.Seats = append(s.Seats[:seat], s.Seats[seat+1:]...)
+	if seat < s.Turn {
+		s.Turn--
+	}
+	if s.Turn >= len(s.Seats) {
+		s.Turn = 0
+	}
+	return nil
+}
+
+// CurrentPlayer is the player whose turn it is, empty while no one is seated
+func (s *Session) CurrentPlayer() string {
+	if len(s.Seats) == 0 {
+		return ""
+	}
+	return s.Seats[s.Turn].Player
+}
+
+// CheckTurn reports whether the player may act now
+func (s *Session) CheckTurn(player string) error {
+	if s.seat(player) < 0 {
+		return ErrNotSeated
+	}
+	if s.CurrentPlayer() != player {
+		return ErrNotYourTurn
+	}
+	return nil
+}
+
+// EndTurn passes the turn to the next seat
+func (s *Session) EndTurn() {
+	if len(s.Seats) > 0 {
+		s.Turn = (s.Turn + 1) % len(s.Seats)
+	}
+}
+
+func (s *Session) seat(player string) int {
+	for i, seat := range s.Seats {
+		if seat.Player == player {
+			return i
+		}
+	}
+	return -1
+}
+
+// Implement BeforeSave hook to encode the decks and seats to JSON
+func (s *Session) BeforeSave(*gorm.DB) error {
+	var err error
+	if s.DecksJSON, err = json.Marshal(s.DeckIDs); err != nil {
+		return err
+	}
+	s.SeatsJSON, err = json.Marshal(s.Seats)
+	return err
+}
+
+// Implement AfterFind hook to decode the decks and seats from JSON
+func (s *Session) AfterFind(*gorm.DB) error {
+	if len(s.DecksJSON) > 0 {
+		if err := json.Unmarshal(s.DecksJSON, &s.DeckIDs); err != nil {
+			return err
+		}
+	}
+	if len(s.SeatsJSON) > 0 {
+		return json.Unmarshal(s.SeatsJSON, &s.Seats)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+	"toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession_JoinAndTakeTurns(t *testing.T) {
+	_, err := model.NewSession(1, nil)
+	assert.Error(t, err)
+
+	session, err := model.NewSession(3, []string{"deck"})
+	require.NoError(t, err)
+	assert.Equal(t, "", session.CurrentPlayer())
+
+	now := time.Now()
+	require.NoError(t, session.Join("alice", now))
+	require.NoError(t, session.Join("bob", now))
+	assert.ErrorIs(t, session.Join("bob", now), model.ErrAlreadySeated)
+	require.NoError(t, session.Join("carol", now))
+	assert.ErrorIs(t, session.Join("dave", now), model.ErrSessionFull)
+
+	assert.NoError(t, session.CheckTurn("alice"))
+	assert.ErrorIs(t, session.CheckTurn("bob"), model.ErrNotYourTurn)
+	assert.ErrorIs(t, session.CheckTurn("dave"), model.ErrNotSeated)
+
+	session.EndTurn()
+	session.EndTurn()
+	assert.Equal(t, "carol", session.CurrentPlayer())
+	session.EndTurn()
+	assert.Equal(t, "alice", session.CurrentPlayer())
+}
+
+func TestSession_LeaveKeepsTurnOrder(t *testing.T) {
+	session, _ := model.NewSession(4, nil)
+	for _, player := range []string{"alice", "bob", "carol"} {
+		require.NoError(t, session.Join(player, time.Now()))
+	}
+	session.EndTurn()
+
+	// An earlier seat leaving keeps the turn with bob
+	require.NoError(t, session.Leave("alice"))
+	assert.Equal(t, "bob", session.CurrentPlayer())
+
+	// The player holding the turn leaving hands it to the next seat
+	require.NoError(t, session.Leave("bob"))
+	assert.Equal(t, "carol", session.CurrentPlayer())
+
+	session.Join("dave", time.Now())
+	session.EndTurn()
+	require.NoError(t, session.Leave("dave"))
+	assert.Equal(t, "carol", session.CurrentPlayer())
+
+	assert.ErrorIs(t, session.Leave("dave"), model.ErrNotSeated)
+}
@@ -0,0 +1,117 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// suitOrder sorts suits the way bridge ranks them, clubs lowest
+var suitOrder = map[string]int{
+	"C": 1,
+	"D": 2,
+	"H": 3,
+	"S": 4,
+}
+
+// CardFilter keeps the cards matching every set criterion
+type CardFilter struct {
+	Suit    string
+	Color   string
+	RankGTE int
+	RankLTE int
+}
+
+// ValidateSortKeys checks a list of sort keys such as "suit" or "-rank",
+// where a leading "-" sorts that key in descending order
+func ValidateSortKeys(keys []string) error {
+	if len(keys) == 0 {
+		return fmt.Errorf("no sort keys given")
+	}
+
+	for _, key := range keys {
+		switch strings.TrimPrefix(key, "-") {
+		case "suit", "rank":
+		default:
+			return fmt.Errorf("invalid sort key: %s", key)
+		}
+	}
+	return nil
+}
+
+// Sort orders the remaining cards by the given keys, ranking them with rules.
+// Cards that compare equal keep their relative order.
+func (d *Deck) Sort(keys []string, rules Ruleset) error {
+	if err := ValidateSortKeys(keys); err != nil {
+		return err
+	}
+
+	sort.SliceStable(d.Cards, func(i, j int) bool {
+		return sortsBefore(d.Cards[i].Code, d.Cards[j].Code, keys, rules)
+	})
+
+	d.Shuffled = false
+	return nil
+}
+
+// Sort orders the cards of the pile the way Deck.Sort orders a deck
+func (p *Pile) Sort(keys []string, rules Ruleset) error {
+	if err := ValidateSortKeys(keys); err != nil {
+		return err
+	}
+	sortCodes(p.Cards, keys, rules)
+	return nil
+}
+
+func sortCodes(codes []string, keys []string, rules Ruleset) {
+	sort.SliceStable(codes, func(i, j int) bool {
+		return sortsBefore(codes[i], codes[j], keys, rules)
+	})
+}
+
+func sortsBefore(a, b string, keys []string, rules Ruleset) bool {
+	for _, key := range keys {
+		x, y := sortValue(a, key, rules), sortValue(b, key, rules)
+		if x == y {
+			continue
+		}
+		if strings.HasPrefix(key, "-") {
+			return x > y
+		}
+		return x < y
+	}
+	return false
+}
+
+func sortValue(code string, key string, rules Ruleset) int {
+	if strings.TrimPrefix(key, "-") == "rank" {
+		return rules.Rank(code)
+	}
+	if len(code) < 2 {
+		return 0
+	}
+	_, suit := splitCode(code)
+	return suitOrder[suit]
+}
+
+// Filter returns the cards matching the filter, ranking them with rules
+func (f CardFilter) Filter(cards []Card, rules Ruleset) []Card {
+	filtered := []Card{}
+	for _, card := range cards {
+		annotated := rules.Annotate(card)
+		if f.Suit != "" && card.Suit != f.Suit {
+			continue
+		}
+		if f.Color != "" && annotated.Color != f.Color {
+			continue
+		}
+		if f.RankGTE != 0 && annotated.Rank < f.RankGTE {
+			continue
+		}
+		if f.RankLTE != 0 && annotated.Rank > f.RankLTE {
+			continue
+		}
+		filtered = append(filtered, card)
+	}
+	return filtered
+}
@@ -0,0 +1,45 @@
+package model_test
+
+import (
+	"testing"
+	"toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSort_BySuitAndRank(t *testing.T) {
+	deck, err := (&model.Deck{}).Create([]string{"KH", "2S", "AH", "10C", "AS", "3C"})
+	require.NoError(t, err)
+	deck.Shuffled = true
+
+	rules, _ := model.LookupRules("ace_high")
+	require.NoError(t, deck.Sort([]string{"suit", "rank"}, rules))
+	assert.Equal(t, []string{"3C", "10C", "KH", "AH", "2S", "AS"}, model.CardCodes(deck.Cards))
+	assert.False(t, deck.Shuffled)
+
+	rules, _ = model.LookupRules("ace_low")
+	require.NoError(t, deck.Sort([]string{"-rank"}, rules))
+	assert.Equal(t, []string{"KH", "10C", "3C", "2S", "AH", "AS"}, model.CardCodes(deck.Cards))
+}
+
+func TestSort_InvalidKey(t *testing.T) {
+	deck, _ := (&model.Deck{}).Create([]string{"KH", "2S"})
+	rules, _ := model.LookupRules("ace_high")
+
+	assert.EqualError(t, deck.Sort([]string{"colour"}, rules), "invalid sort key: colour")
+}
+
+func TestCardFilter(t *testing.T) {
+	deck, _ := (&model.Deck{}).Create([]string{"KH", "2S", "AH", "10C", "5D"})
+	rules, _ := model.LookupRules("ace_high")
+
+	hearts := model.CardFilter{Suit: "HEARTS"}.Filter(deck.Cards, rules)
+	assert.Equal(t, []string{"KH", "AH"}, model.CardCodes(hearts))
+
+	highRed := model.CardFilter{Color: "RED", RankGTE: 10}.Filter(deck.Cards, rules)
+	assert.Equal(t, []string{"KH", "AH"}, model.CardCodes(highRed))
+
+	low := model.CardFilter{RankLTE: 5}.Filter(deck.Cards, rules)
+	assert.Equal(t, []string{"2S", "5D"}, model.CardCodes(low))
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrQuotaExceeded is returned when a tenant would outgrow one of its quotas
+var ErrQuotaExceeded = errors.New("tenant quota exceeded")
+
+// Quota caps what a tenant may store; zero fields are unlimited
+type Quota struct {
+	// CardTypes caps the custom card types of the tenant
+	CardTypes int `json:"card_types"`
+	// TypeCards caps the cards of each custom card type
+	TypeCards int `json:"type_cards"`
+	// LiveDecks caps the decks of the tenant that have not expired yet
+	LiveDecks int `json:"live_decks"`
+}
+
+// Tenant is a client app sharing the deployment with others. It only needs
+// a row to override the server's default quota, zero fields keep the default
+type Tenant struct {
+	ID    string `json:"tenant" gorm:"primaryKey"`
+	Quota `gorm:"embedded"`
+}
+
+// LoadTenant reads the tenant's row, falling back to an empty one
+func LoadTenant(db *gorm.DB, id string) (Tenant, error) {
+	tenant := Tenant{ID: id}
+	err := db.Where("id = ?", id).Limit(1).Find(&tenant).Error
+	return tenant, err
+}
+
+// Limits is the tenant's quota with the defaults filled in
+func (t Tenant) Limits(defaults Quota) Quota {
+	limits := t.Quota
+	if limits.CardTypes == 0 {
+		limits.CardTypes = defaults.CardTypes
+	}
+	if limits.TypeCards == 0 {
+		limits.TypeCards = defaults.TypeCards
+	}
+	if limits.LiveDecks == 0 {
+		limits.LiveDecks = defaults.LiveDecks
+	}
+	return limits
+}
+
+// Within reports whether a count fits under a limit, zero being unlimited
+func Within(count, limit int) bool {
+	return limit == 0 || count <= limit
+}
@@ -0,0 +1,23 @@
+package model_test
+
+import (
+	"testing"
+	"toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenant_Limits(t *testing.T) {
+	defaults := model.Quota{CardTypes: 10, TypeCards: 200, LiveDecks: 1000}
+
+	assert.Equal(t, defaults, model.Tenant{ID: "studio"}.Limits(defaults))
+	assert.Equal(t,
+		model.Quota{CardTypes: 3, TypeCards: 200, LiveDecks: 1000},
+		model.Tenant{ID: "studio", Quota: model.Quota{CardTypes: 3}}.Limits(defaults))
+}
+
+func TestWithin(t *testing.T) {
+	assert.True(t, model.Within(5, 0))
+	assert.True(t, model.Within(5, 5))
+	assert.False(t, model.Within(6, 5))
+}
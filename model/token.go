@@ -0,0 +1,67 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Roles a deck token grants, each allowing everything the ones before it do
+const (
+	RoleReadOnly = "READ_ONLY"
+	RoleDraw     = "DRAW"
+	RoleDealer   = "DEALER"
+	RoleOwner    = "OWNER"
+)
+
+var roleRanks = map[string]int{
+	RoleReadOnly: 1,
+	RoleDraw:     2,
+	RoleDealer:   3,
+	RoleOwner:    4,
+}
+
+// DeckToken grants its holder a role on a deck. Only the SHA-256 of the
+// token is stored, the token itself is handed out once when minted
+type DeckToken struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	DeckID    string    `json:"deck_id" gorm:"index"`
+	Hash      string    `json:"-" gorm:"uniqueIndex"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ParseRole maps a role name in any case to its constant
+func ParseRole(name string) (string, error) {
+	role := strings.ToUpper(name)
+	if _, ok := roleRanks[role]; !ok {
+		return "", errors.New("invalid role: " + name)
+	}
+	return role, nil
+}
+
+// NewDeckToken mints a random token with the given role on the deck,
+// returning it alongside the record to store
+func NewDeckToken(deckID, role string) (DeckToken, string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return DeckToken{}, "", err
+	}
+
+	token := hex.EncodeToString(raw)
+	return DeckToken{DeckID: deckID, Hash: HashToken(token), Role: role}, token, nil
+}
+
+// HashToken is how a token is looked up
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Allows reports whether the role includes the required one
+func Allows(role, required string) bool {
+	return roleRanks[role] > 0 && roleRanks[role] >= roleRanks[required]
+}
@@ -0,0 +1,41 @@
+package model_test
+
+import (
+	"testing"
+	"toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRole(t *testing.T) {
+	role, err := model.ParseRole("read_only")
+	require.NoError(t, err)
+	assert.Equal(t, model.RoleReadOnly, role)
+
+	_, err = model.ParseRole("admin")
+	assert.EqualError(t, err, "invalid role: admin")
+}
+
+func TestAllows(t *testing.T) {
+	assert.True(t, model.Allows(model.RoleOwner, model.RoleDealer))
+	assert.True(t, model.Allows(model.RoleDraw, model.RoleDraw))
+	assert.True(t, model.Allows(model.RoleDraw, model.RoleReadOnly))
+	assert.False(t, model.Allows(model.RoleReadOnly, model.RoleDraw))
+	assert.False(t, model.Allows(model.RoleDealer, model.RoleOwner))
+	assert.False(t, model.Allows("", model.RoleReadOnly))
+}
+
+func TestNewDeckToken(t *testing.T) {
+	record, token, err := model.NewDeckToken("deck", model.RoleDraw)
+	require.NoError(t, err)
+
+	assert.Equal(t, "deck", record.DeckID)
+	assert.Equal(t, model.RoleDraw, record.Role)
+	assert.Equal(t, model.HashToken(token), record.Hash)
+	assert.NotEqual(t, token, record.Hash)
+
+	_, other, err := model.NewDeckToken("deck", model.RoleDraw)
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other)
+}
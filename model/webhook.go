@@ -0,0 +1,144 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Webhook events besides the created and drawn entries of the deck log:
+// emptied once a draw takes the last card of a deck, expired once the
+// janitor purges a deck past its TTL
+const (
+	EventEmptied = "emptied"
+	EventExpired = "expired"
+)
+
+// WebhookEvents are the events a webhook may subscribe to
+var WebhookEvents = []string{EventCreated, EventDrawn, EventEmptied, EventExpired}
+
+// States of a webhook delivery
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook calls its URL back on the events of one deck, or of every deck of
+// its tenant when DeckID is empty. Its secret signs every payload, so it is
+// kept as is and only handed out when the webhook is registered. Deleted
+// webhooks stay behind soft-deleted for the deliveries still logged to them
+type Webhook struct {
+	ID         string         `json:"id" gorm:"primaryKey"`
+	Tenant     string         `json:"-" gorm:"index"`
+	DeckID     string         `json:"deck_id,omitempty" gorm:"index"`
+	URL        string         `json:"url"`
+	Secret     string         `json:"-"`
+	EventsJSON []byte         `json:"-" gorm:"column:events"`
+	Events     []string       `json:"events" gorm:"-"`
+	CreatedBy  string         `json:"created_by,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// WebhookDelivery logs one event sent to a webhook and how its attempts
+// went. A pending delivery is attempted again once NextAttemptAt is past, so
+// retries survive a restart
+type WebhookDelivery struct {
+	ID            uint            `json:"id" gorm:"primaryKey"`
+	WebhookID     string          `json:"webhook_id" gorm:"index"`
+	Tenant        string          `json:"-" gorm:"index"`
+	DeckID        string          `json:"deck_id" gorm:"index"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	StatusCode    int             `json:"status_code,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty" gorm:"index"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// NewWebhook checks the URL and events of a webhook and gives it an ID and
+// a random secret; no events subscribes it to all of them
+func NewWebhook(target string, events []string) (Webhook, error) {
+	parsed, err := url.Parse(target)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return Webhook{}, errors.New("invalid webhook url: " + target)
+	}
+
+	subscribed := []string{}
+	for _, event := range events {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if !contains(WebhookEvents, event) {
+			return Webhook{}, errors.New("invalid webhook event: " + event)
+		}
+		if !contains(subscribed, event) {
+			subscribed = append(subscribed, event)
+		}
+	}
+	if len(subscribed) == 0 {
+		subscribed = append(subscribed, WebhookEvents...)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return Webhook{}, err
+	}
+
+	return Webhook{
+		ID:     uuid.New().String(),
+		URL:    target,
+		Secret: hex.EncodeToString(secret),
+		Events: subscribed,
+	}, nil
+}
+
+// Wants reports whether the webhook subscribed to the event
+func (w Webhook) Wants(event string) bool {
+	return contains(w.Events, event)
+}
+
+// SignWebhook is the signature of a payload sent at the given Unix time:
+// the hex HMAC-SHA256 of "timestamp.payload" keyed by the webhook secret
+func SignWebhook(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Implement BeforeSave hook to encode Events field to JSON
+func (w *Webhook) BeforeSave(*gorm.DB) error {
+	var err error
+	w.EventsJSON, err = json.Marshal(w.Events)
+	return err
+}
+
+// Implement AfterFind hook to decode Events field from JSON
+func (w *Webhook) AfterFind(*gorm.DB) error {
+	if len(w.EventsJSON) > 0 {
+		return json.Unmarshal(w.EventsJSON, &w.Events)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package model_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"toggl-test-wiliam/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWebhook(t *testing.T) {
+	hook, err := model.NewWebhook("https://example.com/hooks", []string{"Drawn", "emptied", "drawn"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{model.EventDrawn, model.EventEmptied}, hook.Events)
+	assert.True(t, hook.Wants(model.EventEmptied))
+	assert.False(t, hook.Wants(model.EventCreated))
+	assert.Len(t, hook.Secret, 64)
+	assert.NotEmpty(t, hook.ID)
+
+	hook, err = model.NewWebhook("http://localhost:8080", nil)
+	require.NoError(t, err)
+	assert.Equal(t, model.WebhookEvents, hook.Events)
+
+	_, err = model.NewWebhook("ftp://example.com", nil)
+	assert.EqualError(t, err, "invalid webhook url: ftp://example.com")
+	_, err = model.NewWebhook("https://example.com", []string{"shuffled"})
+	assert.EqualError(t, err, "invalid webhook event: shuffled")
+}
+
+func TestSignWebhook(t *testing.T) {
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(`1700000000.{"event":"drawn"}`))
+
+	signature := model.SignWebhook("secret", 1700000000, []byte(`{"event":"drawn"}`))
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), signature)
+	assert.NotEqual(t, signature, model.SignWebhook("secret", 1700000001, []byte(`{"event":"drawn"}`)))
+}
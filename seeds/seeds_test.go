@@ -1,7 +1,6 @@
 package seeds_test
 
 import (
-	"errors"
 	"testing"
 
 	model "toggl-test-wiliam/model"
@@ -20,10 +19,8 @@ func TestFrenchCardDeck(t *testing.T) {
 	}
 
 	// Migrate and seed data
-	if err = db.AutoMigrate(&model.Card{}, &model.Deck{}); err == nil && db.Migrator().HasTable(&model.Card{}) {
-		if err := db.First(&model.Card{}).Error; errors.Is(err, gorm.ErrRecordNotFound) {
-			seeds.FrenchCardDeck(db)
-		}
+	if err = seeds.Setup(db); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
 	}
 
 	// Retrieve all cards from the database
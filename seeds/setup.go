@@ -0,0 +1,38 @@
+package seeds
+
+import (
+	"errors"
+
+	"toggl-test-wiliam/game/blackjack"
+	"toggl-test-wiliam/game/holdem"
+	"toggl-test-wiliam/game/solitaire"
+	model "toggl-test-wiliam/model"
+
+	"gorm.io/gorm"
+)
+
+// Setup migrates every model and seeds the card catalog when it is still empty
+func Setup(db *gorm.DB) error {
+	err := db.AutoMigrate(
+		&model.Card{},
+		&model.Deck{},
+		&model.DeckEvent{},
+		&model.Pile{},
+		&model.DeckToken{},
+		&model.Session{},
+		&model.Tenant{},
+		&model.Webhook{},
+		&model.WebhookDelivery{},
+		&blackjack.Game{},
+		&holdem.Table{},
+		&solitaire.Game{},
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := db.First(&model.Card{}).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		FrenchCardDeck(db)
+	}
+	return nil
+}